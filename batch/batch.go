@@ -0,0 +1,273 @@
+// Package batch generates QRs in bulk from CSV or JSONL rows.
+//
+// Rows are generated concurrently by a pool of workers and written back in
+// input order with their payload and md5. A row that fails validation is
+// reported in its result and never aborts the rest of the batch.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+// Amount is a decimal amount kept exactly as written in the input.
+// In JSON it may be a number or a string.
+type Amount string
+
+// UnmarshalJSON accepts a JSON number or string without going through float64.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*a = Amount(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("amount must be a number or a string: %w", err)
+	}
+	*a = Amount(n)
+	return nil
+}
+
+// Row is one QR to generate. Empty fields are filled from Options.Defaults.
+type Row struct {
+	ID            string `json:"id,omitempty"`
+	BankAccount   string `json:"bankAccount,omitempty"`
+	MerchantName  string `json:"merchantName,omitempty"`
+	MerchantCity  string `json:"merchantCity,omitempty"`
+	Amount        Amount `json:"amount,omitempty"`
+	Currency      string `json:"currency,omitempty"`
+	StoreLabel    string `json:"storeLabel,omitempty"`
+	PhoneNumber   string `json:"phoneNumber,omitempty"`
+	BillNumber    string `json:"billNumber,omitempty"`
+	TerminalLabel string `json:"terminalLabel,omitempty"`
+	Static        bool   `json:"static,omitempty"`
+}
+
+// Result is the outcome of one row.
+type Result struct {
+	Line  int    `json:"line"` // line of the row in the input file
+	Row   Row    `json:"row"`
+	QR    string `json:"qr,omitempty"`
+	MD5   string `json:"md5,omitempty"`
+	Error string `json:"error,omitempty"`
+	PNG   []byte `json:"-"` // set when Options.ImageSize is positive
+}
+
+// Reader yields rows. Next returns io.EOF after the last row.
+// A row that cannot be parsed is returned with an error wrapped in RowError so the batch can continue.
+type Reader interface {
+	Next() (Row, int, error)
+}
+
+// RowError is a parse error of a single row that does not stop the reader.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Writer receives results in input order.
+type Writer interface {
+	Write(result Result) error
+	Close() error
+}
+
+// Options configures Run.
+type Options struct {
+	// Workers is the number of QRs generated concurrently, runtime.NumCPU() when zero.
+	Workers int
+	// Defaults fills the empty fields of every row, e.g. the account and merchant name shared by all invoices.
+	Defaults Row
+	// ImageSize renders a PNG of this size in pixels for every QR when positive.
+	ImageSize int
+}
+
+// Summary counts the rows of a batch.
+type Summary struct {
+	Rows      int
+	Generated int
+	Failed    int
+}
+
+// job is a row waiting for a worker.
+type job struct {
+	seq    int
+	result Result
+}
+
+// Run generates a QR for every row of r and writes the results to w in input order.
+// It returns early only when the context is cancelled or w or r fail; row errors are counted in the summary.
+func Run(ctx context.Context, k *khqr.KHQR, r Reader, w Writer, opts Options) (Summary, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan job, workers)
+	done := make(chan job, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if j.result.Error == "" {
+					generate(k, &j.result, opts)
+				}
+				select {
+				case done <- j:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	// Read rows until EOF, the reader fails or the batch is cancelled
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		for seq := 0; ; seq++ {
+			row, line, err := r.Next()
+			if errors.Is(err, io.EOF) {
+				readErr <- nil
+				return
+			}
+			result := Result{Line: line, Row: row}
+			var rowErr *RowError
+			if errors.As(err, &rowErr) {
+				result.Error = rowErr.Err.Error()
+			} else if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case jobs <- job{seq: seq, result: result}:
+			case <-ctx.Done():
+				readErr <- ctx.Err()
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Write results in input order, holding back the ones that finish early
+	var summary Summary
+	pending := make(map[int]Result)
+	next := 0
+	var writeErr error
+	for j := range done {
+		pending[j.seq] = j.result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			summary.Rows++
+			if result.Error == "" {
+				summary.Generated++
+			} else {
+				summary.Failed++
+			}
+			if writeErr == nil {
+				if writeErr = w.Write(result); writeErr != nil {
+					cancel()
+				}
+			}
+		}
+	}
+
+	if writeErr != nil {
+		return summary, writeErr
+	}
+	if err := <-readErr; err != nil {
+		return summary, err
+	}
+	return summary, ctx.Err()
+}
+
+// generate creates the QR of the result row, recording any error in the result.
+func generate(k *khqr.KHQR, result *Result, opts Options) {
+	row := withDefaults(result.Row, opts.Defaults)
+	qrOptions := khqr.QROptions{
+		BankAccount:   row.BankAccount,
+		MerchantName:  row.MerchantName,
+		MerchantCity:  row.MerchantCity,
+		Currency:      row.Currency,
+		StoreLabel:    row.StoreLabel,
+		PhoneNumber:   row.PhoneNumber,
+		BillNumber:    row.BillNumber,
+		TerminalLabel: row.TerminalLabel,
+		Static:        row.Static,
+	}
+	if !row.Static {
+		money, err := sdk.ParseMoney(strings.TrimSpace(string(row.Amount)), row.Currency)
+		if err != nil {
+			result.Error = err.Error()
+			return
+		}
+		qrOptions.Money = &money
+	}
+
+	qr, err := k.CreateQRWithOptions(qrOptions)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	result.QR = qr
+	result.MD5 = k.GenerateMD5(qr)
+
+	if opts.ImageSize > 0 {
+		if result.PNG, err = k.GenerateQRImage(qr, opts.ImageSize); err != nil {
+			result.Error = err.Error()
+		}
+	}
+}
+
+// withDefaults fills the empty fields of row from defaults.
+func withDefaults(row, defaults Row) Row {
+	fill := func(value *string, fallback string) {
+		if *value == "" {
+			*value = fallback
+		}
+	}
+	fill(&row.BankAccount, defaults.BankAccount)
+	fill(&row.MerchantName, defaults.MerchantName)
+	fill(&row.MerchantCity, defaults.MerchantCity)
+	fill((*string)(&row.Amount), string(defaults.Amount))
+	fill(&row.Currency, defaults.Currency)
+	fill(&row.StoreLabel, defaults.StoreLabel)
+	fill(&row.PhoneNumber, defaults.PhoneNumber)
+	fill(&row.BillNumber, defaults.BillNumber)
+	fill(&row.TerminalLabel, defaults.TerminalLabel)
+	row.Static = row.Static || defaults.Static
+	return row
+}
@@ -0,0 +1,124 @@
+package batch
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+)
+
+var defaults = Row{BankAccount: "your_name@wing", MerchantName: "Your Name", MerchantCity: "Phnom Penh", Currency: "KHR"}
+
+func TestRunCSV(t *testing.T) {
+	input := "Bill Number,Amount,Currency,Notes\n" +
+		"INV-001,10000,,first\n" +
+		"INV-002,12.50,USD,second\n" +
+		"INV-003,-5,,negative\n" +
+		"INV-004,\"unterminated\n"
+	reader, err := NewCSVReader(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("NewCSVReader() error: %v", err)
+	}
+	var out bytes.Buffer
+	writer := NewCSVWriter(&out)
+
+	summary, err := Run(context.Background(), khqr.NewKHQR(""), reader, writer, Options{Workers: 3, Defaults: defaults})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	writer.Close()
+	if summary != (Summary{Rows: 4, Generated: 2, Failed: 2}) {
+		t.Errorf("Summary = %+v", summary)
+	}
+
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil || len(records) != 5 {
+		t.Fatalf("Output has %d records, %v", len(records), err)
+	}
+	header := records[0]
+	column := func(record []string, name string) string {
+		for i, field := range header {
+			if field == name {
+				return record[i]
+			}
+		}
+		t.Fatalf("Output has no %s column", name)
+		return ""
+	}
+	for i, want := range []string{"INV-001", "INV-002", "INV-003", ""} {
+		if got := column(records[i+1], "billNumber"); got != want {
+			t.Errorf("Record %d is %q, want %q in input order", i+1, got, want)
+		}
+	}
+	if column(records[1], "qr") == "" || len(column(records[2], "md5")) != 32 || column(records[2], "error") != "" {
+		t.Errorf("Generated rows are incomplete: %q", records[1:3])
+	}
+	if !strings.Contains(column(records[3], "error"), "negative") || column(records[4], "error") == "" {
+		t.Errorf("Failed rows are not reported: %q", records[3:])
+	}
+	if column(records[2], "line") != "3" {
+		t.Errorf("Second row is on line %s, want 3", column(records[2], "line"))
+	}
+}
+
+func TestRunJSONLToZip(t *testing.T) {
+	input := `{"id":"a/1","amount":100,"billNumber":"INV-1"}
+{"amount":"7.25","currency":"USD"}
+
+{"amount":1.5,"currency":"KHR"}
+{"unknown":true}
+`
+	var out bytes.Buffer
+	writer, err := NewZipWriter(&out, "jsonl")
+	if err != nil {
+		t.Fatalf("NewZipWriter() error: %v", err)
+	}
+	summary, err := Run(context.Background(), khqr.NewKHQR(""), NewJSONLReader(strings.NewReader(input)), writer, Options{Defaults: defaults, ImageSize: 64})
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if summary != (Summary{Rows: 4, Generated: 2, Failed: 2}) {
+		t.Errorf("Summary = %+v", summary)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatalf("Output is not a zip: %v", err)
+	}
+	var names []string
+	var results []Result
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+		if file.Name == "results.jsonl" {
+			f, _ := file.Open()
+			data, _ := io.ReadAll(f)
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				var result Result
+				json.Unmarshal([]byte(line), &result)
+				results = append(results, result)
+			}
+		}
+	}
+	if strings.Join(names, " ") != "qr/1-a_1.png qr/2-"+results[1].MD5+".png results.jsonl" {
+		t.Errorf("Archive holds %q", names)
+	}
+	if results[2].Line != 4 || !strings.Contains(results[2].Error, "decimal places") || results[3].Line != 5 || results[3].Error == "" {
+		t.Errorf("Failed rows are not reported: %+v", results[2:])
+	}
+}
+
+func TestAmountUnmarshalKeepsDigits(t *testing.T) {
+	var row Row
+	if err := json.Unmarshal([]byte(`{"amount":0.10000000000000001}`), &row); err != nil || row.Amount != "0.10000000000000001" {
+		t.Errorf("Amount = %q, %v", row.Amount, err)
+	}
+}
@@ -0,0 +1,279 @@
+package batch
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// columns are the CSV columns of a row, in output order.
+var columns = []string{"id", "bankAccount", "merchantName", "merchantCity", "amount", "currency", "storeLabel", "phoneNumber", "billNumber", "terminalLabel", "static"}
+
+// rowFields returns pointers to the string fields of a row by column.
+func rowFields(row *Row) map[string]*string {
+	return map[string]*string{
+		"id":            &row.ID,
+		"bankAccount":   &row.BankAccount,
+		"merchantName":  &row.MerchantName,
+		"merchantCity":  &row.MerchantCity,
+		"amount":        (*string)(&row.Amount),
+		"currency":      &row.Currency,
+		"storeLabel":    &row.StoreLabel,
+		"phoneNumber":   &row.PhoneNumber,
+		"billNumber":    &row.BillNumber,
+		"terminalLabel": &row.TerminalLabel,
+	}
+}
+
+// normalizeColumn lets CSV headers use any case and snake, kebab or spaced names, e.g. "Bill Number".
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.NewReplacer("_", "", "-", "", " ", "").Replace(strings.TrimSpace(name)))
+}
+
+// CSVReader reads rows from a CSV file with a header line naming the columns.
+// Unknown columns are ignored.
+type CSVReader struct {
+	r       *csv.Reader
+	columns []string // row column of each CSV field, empty when ignored
+}
+
+// NewCSVReader reads the header and returns a CSVReader for the rows that follow.
+func NewCSVReader(r io.Reader) (*CSVReader, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read CSV header: %w", err)
+	}
+
+	known := make(map[string]string, len(columns))
+	for _, column := range columns {
+		known[normalizeColumn(column)] = column
+	}
+	c := &CSVReader{r: reader, columns: make([]string, len(header))}
+	matched := false
+	for i, name := range header {
+		// The first header field may start with a UTF-8 byte order mark written by spreadsheets
+		c.columns[i] = known[normalizeColumn(strings.TrimPrefix(name, "\uFEFF"))]
+		matched = matched || c.columns[i] != ""
+	}
+	if !matched {
+		return nil, fmt.Errorf("CSV header %q has none of the columns %s", header, strings.Join(columns, ", "))
+	}
+	return c, nil
+}
+
+// Next returns the next row and its line number.
+func (c *CSVReader) Next() (Row, int, error) {
+	record, err := c.r.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return Row{}, parseErr.StartLine, &RowError{Line: parseErr.StartLine, Err: parseErr.Err}
+	}
+	if err != nil {
+		return Row{}, 0, err
+	}
+	line, _ := c.r.FieldPos(0)
+
+	var row Row
+	fields := rowFields(&row)
+	for i, value := range record {
+		if i >= len(c.columns) || c.columns[i] == "" {
+			continue
+		}
+		if c.columns[i] == "static" {
+			if value = strings.TrimSpace(value); value != "" {
+				if row.Static, err = strconv.ParseBool(value); err != nil {
+					return row, line, &RowError{Line: line, Err: fmt.Errorf("invalid static value %q", value)}
+				}
+			}
+			continue
+		}
+		*fields[c.columns[i]] = value
+	}
+	return row, line, nil
+}
+
+// JSONLReader reads one JSON row per line. Blank lines are skipped.
+type JSONLReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewJSONLReader returns a JSONLReader reading from r.
+func NewJSONLReader(r io.Reader) *JSONLReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+	return &JSONLReader{scanner: scanner}
+}
+
+// Next returns the next row and its line number.
+func (j *JSONLReader) Next() (Row, int, error) {
+	for j.scanner.Scan() {
+		j.line++
+		data := bytes.TrimSpace(j.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var row Row
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row); err != nil {
+			return Row{}, j.line, &RowError{Line: j.line, Err: err}
+		}
+		return row, j.line, nil
+	}
+	if err := j.scanner.Err(); err != nil {
+		return Row{}, 0, err
+	}
+	return Row{}, 0, io.EOF
+}
+
+// CSVWriter writes results as CSV: the line, the row columns, then qr, md5 and error.
+type CSVWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVWriter returns a CSVWriter writing to w.
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+// Write writes the result as a CSV record, preceded by the header on the first call.
+func (c *CSVWriter) Write(result Result) error {
+	if !c.header {
+		c.header = true
+		header := append([]string{"line"}, columns...)
+		if err := c.w.Write(append(header, "qr", "md5", "error")); err != nil {
+			return err
+		}
+	}
+	record := []string{strconv.Itoa(result.Line)}
+	fields := rowFields(&result.Row)
+	for _, column := range columns {
+		if column == "static" {
+			record = append(record, strconv.FormatBool(result.Row.Static))
+			continue
+		}
+		record = append(record, *fields[column])
+	}
+	record = append(record, result.QR, result.MD5, result.Error)
+	return c.w.Write(record)
+}
+
+// Close flushes the buffered records.
+func (c *CSVWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// JSONLWriter writes one JSON result per line.
+type JSONLWriter struct {
+	encoder *json.Encoder
+}
+
+// NewJSONLWriter returns a JSONLWriter writing to w.
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &JSONLWriter{encoder: encoder}
+}
+
+// Write writes the result as a JSON line.
+func (j *JSONLWriter) Write(result Result) error {
+	return j.encoder.Encode(result)
+}
+
+// Close does nothing, every result is written as it arrives.
+func (j *JSONLWriter) Close() error {
+	return nil
+}
+
+// ZipWriter writes a zip archive holding a PNG per generated QR and, once closed,
+// the results file in CSV or JSONL format.
+type ZipWriter struct {
+	zip     *zip.Writer
+	name    string
+	buf     bytes.Buffer
+	results Writer
+	names   map[string]bool
+}
+
+// NewZipWriter returns a ZipWriter writing to w, with the results in the format "csv" or "jsonl".
+func NewZipWriter(w io.Writer, format string) (*ZipWriter, error) {
+	z := &ZipWriter{zip: zip.NewWriter(w), names: make(map[string]bool)}
+	switch format {
+	case "csv":
+		z.results = NewCSVWriter(&z.buf)
+	case "jsonl":
+		z.results = NewJSONLWriter(&z.buf)
+	default:
+		return nil, fmt.Errorf("unknown results format %q, use csv or jsonl", format)
+	}
+	z.name = "results." + format
+	return z, nil
+}
+
+// Write adds the PNG of the result to the archive and records the result.
+// The PNG is named after the row ID, bill number or md5, prefixed with the line number.
+func (z *ZipWriter) Write(result Result) error {
+	if len(result.PNG) > 0 {
+		header := &zip.FileHeader{Name: z.imageName(result), Method: zip.Store}
+		entry, err := z.zip.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(result.PNG); err != nil {
+			return err
+		}
+	}
+	return z.results.Write(result)
+}
+
+// Close writes the results file and the zip directory.
+func (z *ZipWriter) Close() error {
+	if err := z.results.Close(); err != nil {
+		return err
+	}
+	entry, err := z.zip.Create(z.name)
+	if err != nil {
+		return err
+	}
+	if _, err := z.buf.WriteTo(entry); err != nil {
+		return err
+	}
+	return z.zip.Close()
+}
+
+// imageName returns a unique file name for the PNG of the result.
+func (z *ZipWriter) imageName(result Result) string {
+	label := result.Row.ID
+	if label == "" {
+		label = result.Row.BillNumber
+	}
+	if label == "" {
+		label = result.MD5
+	}
+	label = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, label)
+	name := path.Join("qr", fmt.Sprintf("%d-%s.png", result.Line, label))
+	for i := 2; z.names[name]; i++ {
+		name = path.Join("qr", fmt.Sprintf("%d-%s-%d.png", result.Line, label, i))
+	}
+	z.names[name] = true
+	return name
+}
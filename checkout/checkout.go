@@ -0,0 +1,248 @@
+// Package checkout serves a hosted checkout page for stored payments.
+//
+// The page shows the KHQR card with the amount and a countdown to expiry,
+// offers an "Open in Bakong" button on mobile and redirects to the success
+// URL as soon as the payment is detected.
+package checkout
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/sdk"
+	"github.com/chhunneng/bakong-khqr/store"
+	"github.com/chhunneng/bakong-khqr/stream"
+)
+
+//go:embed page.html
+var pageFS embed.FS
+
+var pageTemplate = template.Must(template.ParseFS(pageFS, "page.html"))
+
+// Config configures a checkout Handler.
+type Config struct {
+	// Store holds the payments that can be checked out, looked up by md5.
+	Store store.Store
+	// Poller is shared with every open checkout page.
+	Poller *stream.Poller
+	// SuccessURL receives the payer once the payment is detected, with the md5 added as a query parameter.
+	SuccessURL string
+	// CancelURL is linked from the page, optional.
+	CancelURL string
+	// AppName, AppIconURL and Callback are passed to KHQR.GenerateDeeplink for the mobile button.
+	AppName    string
+	AppIconURL string
+	Callback   string
+	// QRSize is the QR image size in pixels, 320 when zero.
+	QRSize int
+	// OnError is called when a payment the poller found paid cannot be recorded in the store, optional.
+	OnError func(error)
+}
+
+// maxDeeplinks bounds the deeplink cache, entries are also dropped once a payment is paid or expired.
+const maxDeeplinks = 10000
+
+// Handler serves GET /{md5} as the checkout page and GET /{md5}/status as its status stream.
+// Mount it under a prefix with http.StripPrefix.
+type Handler struct {
+	khqr   *khqr.KHQR
+	config Config
+	mux    *http.ServeMux
+	status *stream.Handler
+	now    func() time.Time
+
+	mu        sync.Mutex
+	deeplinks map[string]string
+}
+
+// New initializes and returns a checkout Handler.
+func New(k *khqr.KHQR, config Config) *Handler {
+	if config.QRSize <= 0 {
+		config.QRSize = 320
+	}
+	h := &Handler{
+		khqr:      k,
+		config:    config,
+		mux:       http.NewServeMux(),
+		status:    stream.NewHandler(config.Poller, stream.KnownPayments(config.Store)),
+		now:       time.Now,
+		deeplinks: make(map[string]string),
+	}
+	h.mux.HandleFunc("GET /{md5}", h.handlePage)
+	h.mux.Handle("GET /{md5}/status", h.status)
+	config.Poller.OnPaid(h.markPaid)
+	return h
+}
+
+// markPaid records a payment the poller found paid, so reloading the page redirects to the success URL.
+func (h *Handler) markPaid(md5 string, at time.Time) {
+	h.forgetDeeplink(md5)
+	err := h.config.Store.SwapStatus(context.Background(), md5, store.StatusUnpaid, store.StatusPaid, at)
+	// The poller is shared, so the md5 may belong to another store or already be recorded as paid
+	if err != nil && !errors.Is(err, store.ErrNotFound) && !errors.Is(err, store.ErrStatusChanged) && h.config.OnError != nil {
+		h.config.OnError(err)
+	}
+}
+
+// ServeHTTP serves the checkout page or its status stream.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// pageData is the data rendered by page.html.
+type pageData struct {
+	MD5          string
+	MerchantName string
+	Amount       string
+	Currency     string
+	BillNumber   string
+	QRImage      template.URL
+	Deeplink     string
+	ExpiresAt    int64 // Unix milliseconds, 0 when the QR does not expire
+	Remaining    string
+	Expired      bool
+	StatusURL    string
+	SuccessURL   string
+	CancelURL    string
+}
+
+func (h *Handler) handlePage(w http.ResponseWriter, r *http.Request) {
+	md5 := strings.ToLower(r.PathValue("md5"))
+	payment, err := h.config.Store.Get(r.Context(), md5)
+	if errors.Is(err, store.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "unable to load payment", http.StatusInternalServerError)
+		return
+	}
+
+	successURL := h.successURL(md5)
+	if payment.Status == store.StatusPaid {
+		http.Redirect(w, r, successURL, http.StatusSeeOther)
+		return
+	}
+
+	decoded, err := h.khqr.Decode(payment.QR)
+	if err != nil {
+		http.Error(w, "stored QR is invalid", http.StatusInternalServerError)
+		return
+	}
+	pngData, err := h.khqr.GenerateQRImage(payment.QR, h.config.QRSize)
+	if err != nil {
+		http.Error(w, "unable to render QR", http.StatusInternalServerError)
+		return
+	}
+
+	now := h.now()
+	data := pageData{
+		MD5:          md5,
+		MerchantName: decoded.MerchantName,
+		Amount:       formatAmount(payment.Amount, payment.Currency),
+		Currency:     payment.Currency,
+		BillNumber:   payment.BillNumber,
+		QRImage:      template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData)),
+		Expired:      payment.Expired(now) || payment.Status == store.StatusExpired || payment.Status == store.StatusCancelled,
+		StatusURL:    md5 + "/status",
+		SuccessURL:   successURL,
+		CancelURL:    h.config.CancelURL,
+	}
+	if !payment.ExpiresAt.IsZero() {
+		data.ExpiresAt = payment.ExpiresAt.UnixMilli()
+		data.Remaining = formatRemaining(payment.ExpiresAt.Sub(now))
+	}
+	if data.Expired {
+		h.forgetDeeplink(md5)
+	} else {
+		data.Deeplink = h.deeplink(md5, payment.QR)
+	}
+
+	var buf bytes.Buffer
+	if err := pageTemplate.Execute(&buf, data); err != nil {
+		http.Error(w, "unable to render page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+	if data.Expired {
+		w.WriteHeader(http.StatusGone)
+	}
+	buf.WriteTo(w)
+}
+
+// deeplink returns the Bakong deeplink of the QR, generated once per md5.
+// An empty link hides the button, e.g. when no Bakong token is configured.
+func (h *Handler) deeplink(md5, qr string) string {
+	h.mu.Lock()
+	link, ok := h.deeplinks[md5]
+	h.mu.Unlock()
+	if ok {
+		return link
+	}
+
+	link, err := h.khqr.GenerateDeeplink(qr, h.config.Callback, h.config.AppIconURL, h.config.AppName)
+	if err != nil {
+		return ""
+	}
+	h.mu.Lock()
+	if len(h.deeplinks) >= maxDeeplinks {
+		// Drop an arbitrary entry, it is generated again if its page is still viewed
+		for cached := range h.deeplinks {
+			delete(h.deeplinks, cached)
+			break
+		}
+	}
+	h.deeplinks[md5] = link
+	h.mu.Unlock()
+	return link
+}
+
+// forgetDeeplink drops the cached deeplink of a payment that can no longer be paid.
+func (h *Handler) forgetDeeplink(md5 string) {
+	h.mu.Lock()
+	delete(h.deeplinks, md5)
+	h.mu.Unlock()
+}
+
+// successURL adds the md5 to the configured success URL.
+func (h *Handler) successURL(md5 string) string {
+	target, err := url.Parse(h.config.SuccessURL)
+	if err != nil || h.config.SuccessURL == "" {
+		return ""
+	}
+	query := target.Query()
+	query.Set("md5", md5)
+	target.RawQuery = query.Encode()
+	return target.String()
+}
+
+// formatAmount groups the digits of a stored amount for display, with all decimal places of the currency.
+func formatAmount(amount, currency string) string {
+	money, err := sdk.ParseMoney(amount, currency)
+	if err != nil {
+		return amount
+	}
+	return money.Format()
+}
+
+// formatRemaining formats the time left until expiry as m:ss.
+func formatRemaining(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	seconds := int(d.Round(time.Second).Seconds())
+	return strconv.Itoa(seconds/60) + ":" + strconv.Itoa(seconds%60/10) + strconv.Itoa(seconds%10)
+}
@@ -0,0 +1,129 @@
+package checkout
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/store"
+	"github.com/chhunneng/bakong-khqr/stream"
+)
+
+type unpaidChecker struct{}
+
+func (unpaidChecker) CheckBulkPayments(md5List []string) ([]string, error) {
+	return nil, nil
+}
+
+func newTestHandler(t *testing.T) (*Handler, *khqr.KHQR, store.Store) {
+	t.Helper()
+	khqrInstance := khqr.NewKHQR("")
+	payments := store.NewMemoryStore()
+	khqrInstance.SetStore(payments, 10*time.Minute)
+	h := New(khqrInstance, Config{
+		Store:      payments,
+		Poller:     stream.NewPoller(unpaidChecker{}, time.Second),
+		SuccessURL: "https://shop.example.com/thanks?order=1001",
+	})
+	return h, khqrInstance, payments
+}
+
+func get(h http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestCheckoutPage(t *testing.T) {
+	h, khqrInstance, _ := newTestHandler(t)
+	qr, err := khqrInstance.CreateQR("your_name@wing", "Your Name", "Phnom Penh", 25000, "KHR", "", "", "TRX019283775", "", false)
+	if err != nil {
+		t.Fatalf("Failed to create QR: %v", err)
+	}
+	md5 := khqrInstance.GenerateMD5(qr)
+
+	rec := get(h, "/"+md5)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET page = %d %s", rec.Code, rec.Body.String())
+	}
+	page := rec.Body.String()
+	for _, want := range []string{"Your Name", "25,000", "KHR", "TRX019283775", "data:image/png;base64,", md5 + `/status`, "Expires in", "10:00"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Page does not contain %q", want)
+		}
+	}
+	if strings.Contains(page, "Open in Bakong") {
+		t.Error("Page shows a deeplink button without a Bakong token")
+	}
+}
+
+func TestCheckoutRedirectsPaidPayments(t *testing.T) {
+	h, khqrInstance, payments := newTestHandler(t)
+	qr, _ := khqrInstance.CreateQR("your_name@wing", "Your Name", "Phnom Penh", 5, "USD", "", "", "", "", false)
+	md5 := khqrInstance.GenerateMD5(qr)
+	payments.UpdateStatus(context.Background(), md5, store.StatusPaid, time.Now())
+
+	rec := get(h, "/"+md5)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "https://shop.example.com/thanks?md5="+md5+"&order=1001" {
+		t.Errorf("GET paid page = %d to %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+type paidChecker struct{}
+
+func (paidChecker) CheckBulkPayments(md5List []string) ([]string, error) {
+	return md5List, nil
+}
+
+func TestCheckoutRecordsPaymentsFoundByThePoller(t *testing.T) {
+	khqrInstance := khqr.NewKHQR("")
+	payments := store.NewMemoryStore()
+	khqrInstance.SetStore(payments, 10*time.Minute)
+	poller := stream.NewPoller(paidChecker{}, time.Second)
+	h := New(khqrInstance, Config{Store: payments, Poller: poller, SuccessURL: "https://shop.example.com/thanks"})
+	qr, _ := khqrInstance.CreateQR("your_name@wing", "Your Name", "Phnom Penh", 5, "USD", "", "", "", "", false)
+	md5 := khqrInstance.GenerateMD5(qr)
+
+	// An open page watches the payment until the poller finds it paid
+	_, _, unsubscribe := poller.Subscribe(md5)
+	defer unsubscribe()
+	poller.Poll()
+
+	if payment, _ := payments.Get(context.Background(), md5); payment.Status != store.StatusPaid {
+		t.Errorf("Stored status = %s, want PAID", payment.Status)
+	}
+	if rec := get(h, "/"+md5); rec.Code != http.StatusSeeOther {
+		t.Errorf("GET page after payment = %d, want a redirect", rec.Code)
+	}
+}
+
+func TestCheckoutExpiredAndMissing(t *testing.T) {
+	h, khqrInstance, _ := newTestHandler(t)
+	qr, _ := khqrInstance.CreateQR("your_name@wing", "Your Name", "Phnom Penh", 5, "USD", "", "", "", "", false)
+	h.now = func() time.Time { return time.Now().Add(time.Hour) }
+
+	rec := get(h, "/"+khqrInstance.GenerateMD5(qr))
+	if rec.Code != http.StatusGone || !strings.Contains(rec.Body.String(), "This QR has expired") {
+		t.Errorf("GET expired page = %d", rec.Code)
+	}
+	if rec := get(h, "/dfcabf4598d1c405a75540a3d4ca099d"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET unknown page = %d, want 404", rec.Code)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	for _, tc := range []struct{ amount, currency, want string }{
+		{"00000010000", "KHR", "10,000"},
+		{"1234567.5", "USD", "1,234,567.50"},
+		{"5", "USD", "5.00"},
+		{"", "USD", ""},
+	} {
+		if got := formatAmount(tc.amount, tc.currency); got != tc.want {
+			t.Errorf("formatAmount(%q, %q) = %q, want %q", tc.amount, tc.currency, got, tc.want)
+		}
+	}
+}
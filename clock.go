@@ -0,0 +1,27 @@
+package khqr
+
+import "time"
+
+// Clock tells KHQR the current time. It is used for the QR creation timestamp and for store records.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the default Clock, reading the system time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// FixedClock is a Clock that always returns the same time, for reproducible QRs in tests.
+type FixedClock time.Time
+
+// Now returns the fixed time.
+func (c FixedClock) Now() time.Time { return time.Time(c) }
+
+// Method to set the clock used for QR timestamps, nil restores the system clock
+func (khqr *KHQR) SetClock(clock Clock) {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	khqr.clock = clock
+}
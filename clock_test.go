@@ -0,0 +1,42 @@
+package khqr
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreateQRWithFixedClock(t *testing.T) {
+	createdAt := time.UnixMilli(1700000000000)
+	khqrInstance := NewKHQR("")
+	khqrInstance.SetClock(FixedClock(createdAt))
+
+	const golden = "00020101021229180014your_name@wing520459995802KH5909Your Name6010Phnom Penh991700131700000000000540510000530311662540112TRX0192837750211855123456780305MShop0710Cashier-0163049DA0"
+	qr, err := khqrInstance.CreateQR("your_name@wing", "Your Name", "Phnom Penh", 10000, "KHR", "MShop", "85512345678", "TRX019283775", "Cashier-01", false)
+	if err != nil {
+		t.Fatalf("Failed to create QR: %v", err)
+	}
+	if qr != golden {
+		t.Errorf("CreateQR() = %q, want %q", qr, golden)
+	}
+
+	// An explicit creation time wins over the clock and reproduces the same QR
+	khqrInstance.SetClock(nil)
+	again, err := khqrInstance.CreateQRWithOptions(QROptions{
+		BankAccount:   "your_name@wing",
+		MerchantName:  "Your Name",
+		MerchantCity:  "Phnom Penh",
+		Amount:        10000,
+		Currency:      "KHR",
+		StoreLabel:    "MShop",
+		PhoneNumber:   "85512345678",
+		BillNumber:    "TRX019283775",
+		TerminalLabel: "Cashier-01",
+		CreatedAt:     createdAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create QR: %v", err)
+	}
+	if again != qr || khqrInstance.GenerateMD5(again) != khqrInstance.GenerateMD5(qr) {
+		t.Errorf("CreateQRWithOptions() = %q, want %q", again, qr)
+	}
+}
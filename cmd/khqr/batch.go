@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/batch"
+)
+
+func runBatch(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("batch", "[-in <rows.csv|rows.jsonl|->] [-o <file>] [flags]", stderr)
+	input := flags.String("in", "-", "CSV or JSONL file of rows, - for stdin")
+	format := flags.String("format", "", "input and output format: csv or jsonl, from the -in extension by default")
+	output := flags.String("o", "", "write results to this file instead of stdout")
+	zipImages := flags.Bool("zip", false, "write a zip holding a PNG per QR and the results file")
+	size := flags.Int("size", 512, "PNG image size in pixels, with -zip")
+	workers := flags.Int("workers", 0, "QRs generated concurrently, the number of CPUs by default")
+	bankAccount := flags.String("account", "", "default Bakong account ID for rows without one")
+	merchantName := flags.String("name", "", "default merchant name")
+	merchantCity := flags.String("city", "Phnom Penh", "default merchant city")
+	currency := flags.String("currency", "KHR", "default transaction currency")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	if *format == "" {
+		*format = "csv"
+		if ext := strings.ToLower(filepath.Ext(*input)); ext == ".jsonl" || ext == ".ndjson" {
+			*format = "jsonl"
+		}
+	}
+	if *format != "csv" && *format != "jsonl" {
+		fmt.Fprintf(stderr, "khqr batch: unknown format %q, use csv or jsonl\n", *format)
+		return exitUsage
+	}
+
+	in := stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			fmt.Fprintln(stderr, "khqr batch:", err)
+			return exitError
+		}
+		defer f.Close()
+		in = f
+	}
+	var reader batch.Reader
+	if *format == "csv" {
+		csvReader, err := batch.NewCSVReader(in)
+		if err != nil {
+			fmt.Fprintln(stderr, "khqr batch:", err)
+			return exitError
+		}
+		reader = csvReader
+	} else {
+		reader = batch.NewJSONLReader(in)
+	}
+
+	out := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(stderr, "khqr batch:", err)
+			return exitError
+		}
+		defer f.Close()
+		out = f
+	}
+	var writer batch.Writer
+	imageSize := 0
+	switch {
+	case *zipImages:
+		zipWriter, err := batch.NewZipWriter(out, *format)
+		if err != nil {
+			fmt.Fprintln(stderr, "khqr batch:", err)
+			return exitUsage
+		}
+		writer, imageSize = zipWriter, *size
+	case *format == "csv":
+		writer = batch.NewCSVWriter(out)
+	default:
+		writer = batch.NewJSONLWriter(out)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	summary, err := batch.Run(ctx, khqr.NewKHQR(""), reader, writer, batch.Options{
+		Workers:   *workers,
+		ImageSize: imageSize,
+		Defaults: batch.Row{
+			BankAccount:  *bankAccount,
+			MerchantName: *merchantName,
+			MerchantCity: *merchantCity,
+			Currency:     *currency,
+		},
+	})
+	// Close even after an error so the results written so far are flushed
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	fmt.Fprintf(stderr, "khqr batch: %d rows, %d generated, %d failed\n", summary.Rows, summary.Generated, summary.Failed)
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case err != nil:
+		fmt.Fprintln(stderr, "khqr batch:", err)
+		return exitError
+	case summary.Failed > 0:
+		return exitError
+	}
+	return exitOK
+}
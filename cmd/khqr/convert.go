@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+func runConvert(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("convert", "-amount <amount> [-currency <code>] [-bill <number>] <static payload|->", stderr)
+	amount := flags.String("amount", "", "amount to charge, e.g. 5000 or 1.25 (required)")
+	currency := flags.String("currency", "", "transaction currency, the currency of the static QR by default")
+	billNumber := flags.String("bill", "", "bill number")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	qr, ok := payloadArg(flags, stdin)
+	if !ok || *amount == "" {
+		flags.Usage()
+		return exitUsage
+	}
+
+	instance := khqr.NewKHQR("")
+	if *currency == "" {
+		decoded, err := instance.Decode(qr)
+		if err != nil {
+			fmt.Fprintln(stderr, "khqr convert:", err)
+			return exitError
+		}
+		*currency = decoded.TransactionCurrency
+	}
+	money, err := sdk.ParseMoney(*amount, *currency)
+	if err != nil {
+		fmt.Fprintln(stderr, "khqr convert:", err)
+		return exitUsage
+	}
+	dynamic, err := instance.ConvertToDynamic(qr, money, *billNumber)
+	if err != nil {
+		fmt.Fprintln(stderr, "khqr convert:", err)
+		return exitError
+	}
+	fmt.Fprintln(stdout, dynamic)
+	return exitOK
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+func runDecode(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("decode", "[-image <file>] [<payload> | -]", stderr)
+	imagePath := flags.String("image", "", "read the QR code from a PNG or JPEG file")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	instance := khqr.NewKHQR("")
+	var decoded *sdk.DecodedQR
+	var err error
+	if *imagePath != "" {
+		if flags.NArg() != 0 {
+			flags.Usage()
+			return exitUsage
+		}
+		decoded, err = instance.DecodeImageFile(*imagePath)
+	} else {
+		qr, ok := payloadArg(flags, stdin)
+		if !ok {
+			flags.Usage()
+			return exitUsage
+		}
+		decoded, err = instance.Decode(qr)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "khqr decode:", err)
+		return exitError
+	}
+
+	printDecoded(stdout, decoded)
+	if !decoded.CRCValid {
+		return exitError
+	}
+	return exitOK
+}
+
+func runVerify(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("verify", "<payload> | -", stderr)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	qr, ok := payloadArg(flags, stdin)
+	if !ok {
+		flags.Usage()
+		return exitUsage
+	}
+
+	if !khqr.NewKHQR("").Verify(qr) {
+		fmt.Fprintln(stdout, "invalid")
+		return exitError
+	}
+	fmt.Fprintln(stdout, "valid")
+	return exitOK
+}
+
+func runMD5(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("md5", "<payload> | -", stderr)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	qr, ok := payloadArg(flags, stdin)
+	if !ok {
+		flags.Usage()
+		return exitUsage
+	}
+
+	fmt.Fprintln(stdout, khqr.NewKHQR("").GenerateMD5(qr))
+	return exitOK
+}
+
+// printDecoded writes the non-empty decoded fields as an aligned table.
+func printDecoded(w io.Writer, decoded *sdk.DecodedQR) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	rows := []struct{ name, value string }{
+		{"Payload format", decoded.PayloadFormatIndicator},
+		{"Point of initiation", decoded.PointOfInitiation},
+		{"Merchant type", decoded.MerchantType},
+		{"Bank account", decoded.BankAccount},
+		{"Bank", decoded.BankName},
+		{"Merchant ID", decoded.MerchantID},
+		{"Acquiring bank", decoded.AcquiringBank},
+		{"Category code", decoded.MerchantCategoryCode},
+		{"Country code", decoded.CountryCode},
+		{"Merchant name", decoded.MerchantName},
+		{"Merchant city", decoded.MerchantCity},
+		{"Timestamp", decoded.Timestamp},
+		{"Amount", decoded.Amount},
+		{"Currency", decoded.TransactionCurrency},
+		{"Bill number", decoded.BillNumber},
+		{"Mobile number", decoded.MobileNumber},
+		{"Store label", decoded.StoreLabel},
+		{"Terminal label", decoded.TerminalLabel},
+		{"Purpose", decoded.PurposeOfTransaction},
+		{"Name (alternate)", decoded.MerchantNameAlternate},
+		{"City (alternate)", decoded.MerchantCityAlternate},
+		{"CRC", decoded.CRC},
+	}
+	for _, row := range rows {
+		if row.value != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", row.name, row.value)
+		}
+	}
+	if decoded.CRCValid {
+		fmt.Fprintln(tw, "CRC valid:\tyes")
+	} else {
+		fmt.Fprintln(tw, "CRC valid:\tNO")
+	}
+	tw.Flush()
+}
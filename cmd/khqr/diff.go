@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+func runDiff(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("diff", "[-all] [-ignore <tags>] [-json] <payload|-> <payload|->", stderr)
+	all := flags.Bool("all", false, "also compare the timestamp and CRC")
+	ignore := flags.String("ignore", "", "comma separated tags or sub-tags to leave out, e.g. 62.07,64")
+	asJSON := flags.Bool("json", false, "print the differences as a JSON array")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 2 || flags.Arg(0) == "-" && flags.Arg(1) == "-" {
+		flags.Usage()
+		return exitUsage
+	}
+	qrs := flags.Args()
+	for i, qr := range qrs {
+		if qr == "-" {
+			line, err := bufio.NewReader(stdin).ReadString('\n')
+			if err != nil && err != io.EOF {
+				fmt.Fprintln(stderr, "khqr diff:", err)
+				return exitError
+			}
+			qrs[i] = strings.TrimSpace(line)
+		}
+	}
+
+	opts := sdk.DiffOptions{IncludeVolatile: *all}
+	if *ignore != "" {
+		opts.Ignore = strings.Split(*ignore, ",")
+	}
+	instance := khqr.NewKHQR("")
+	diffs, err := instance.Diff(qrs[0], qrs[1], opts)
+	if err != nil {
+		fmt.Fprintln(stderr, "khqr diff:", err)
+		return exitError
+	}
+	for i, name := range []string{"first", "second"} {
+		if !instance.Verify(qrs[i]) {
+			fmt.Fprintf(stderr, "khqr diff: warning: the %s QR has an invalid CRC\n", name)
+		}
+	}
+
+	if *asJSON {
+		if diffs == nil {
+			diffs = []sdk.FieldDiff{}
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(diffs)
+	} else {
+		for _, diff := range diffs {
+			fmt.Fprintln(stdout, diff)
+		}
+	}
+	if len(diffs) > 0 {
+		return exitError
+	}
+	return exitOK
+}
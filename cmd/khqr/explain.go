@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+)
+
+func runExplain(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("explain", "[-json] <payload|->", stderr)
+	asJSON := flags.Bool("json", false, "print the tree as JSON")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	qr, ok := payloadArg(flags, stdin)
+	if !ok {
+		flags.Usage()
+		return exitUsage
+	}
+
+	explanation := khqr.NewKHQR("").Explain(qr)
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(explanation)
+	} else {
+		fmt.Fprint(stdout, explanation)
+	}
+	if !explanation.Valid() {
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/gateway"
+	"github.com/chhunneng/bakong-khqr/server"
+)
+
+func runGateway(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("gateway", "-clients <file> [flags]", stderr)
+	addr := flags.String("addr", ":8081", "address to listen on")
+	token := tokenFlag(flags)
+	clientsPath := flags.String("clients", "", `JSON file listing clients: [{"id":"web","apiKey":"...","rate":1,"burst":5}] (required)`)
+	adminKey := flags.String("admin-key", os.Getenv("KHQR_ADMIN_KEY"), "key the backend uses to claim md5s for clients (default $KHQR_ADMIN_KEY)")
+	origins := flags.String("origins", "", "comma-separated browser origins allowed to call the gateway")
+	claimTTL := flags.Duration("claim-ttl", gateway.DefaultClaimTTL, "how long a client may query an md5 after it is claimed")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 0 || *clientsPath == "" {
+		flags.Usage()
+		return exitUsage
+	}
+
+	data, err := os.ReadFile(*clientsPath)
+	if err != nil {
+		fmt.Fprintln(stderr, "khqr gateway:", err)
+		return exitError
+	}
+	var clients []gateway.Client
+	if err := json.Unmarshal(data, &clients); err != nil {
+		fmt.Fprintf(stderr, "khqr gateway: invalid clients file: %v\n", err)
+		return exitError
+	}
+	if *adminKey == "" {
+		fmt.Fprintln(stderr, "khqr gateway: warning: no admin key, md5s cannot be claimed for clients")
+	}
+
+	config := gateway.Config{Clients: clients, AdminKey: *adminKey, ClaimTTL: *claimTTL}
+	if *origins != "" {
+		config.AllowedOrigins = strings.Split(*origins, ",")
+	}
+	httpServer := server.NewHTTPServer(*addr, gateway.New(khqr.NewKHQR(*token), config))
+	return serveUntilSignal(httpServer, stdout, stderr)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+func runGenerate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("generate", "-account <id> -name <name> [flags]", stderr)
+	bankAccount := flags.String("account", "", "Bakong account ID, e.g. your_name@wing (required)")
+	merchantName := flags.String("name", "", "merchant name (required)")
+	merchantCity := flags.String("city", "Phnom Penh", "merchant city")
+	amount := flags.Float64("amount", 0, "transaction amount, ignored for static QRs")
+	currency := flags.String("currency", "KHR", "transaction currency, KHR or USD")
+	storeLabel := flags.String("store", "", "store label")
+	phoneNumber := flags.String("phone", "", "merchant mobile number, e.g. 012345678 or +85512345678")
+	billNumber := flags.String("bill", "", "bill number")
+	terminalLabel := flags.String("terminal", "", "terminal label")
+	static := flags.Bool("static", false, "create a static QR without an amount")
+	createdAt := flags.String("created-at", "", "RFC 3339 creation time to encode instead of now, for reproducible QRs")
+	format := flags.String("format", "text", "output format: text, png or terminal")
+	output := flags.String("o", "", "write to this file instead of stdout")
+	size := flags.Int("size", 512, "PNG image size in pixels")
+	ansi := flags.Bool("ansi", false, "draw terminal output with ANSI colours, for light or unknown terminal themes")
+	invert := flags.Bool("invert", false, "invert terminal output for light terminal backgrounds")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 0 || *bankAccount == "" || *merchantName == "" {
+		flags.Usage()
+		return exitUsage
+	}
+
+	opts := khqr.QROptions{
+		BankAccount:   *bankAccount,
+		MerchantName:  *merchantName,
+		MerchantCity:  *merchantCity,
+		Amount:        *amount,
+		Currency:      *currency,
+		StoreLabel:    *storeLabel,
+		PhoneNumber:   *phoneNumber,
+		BillNumber:    *billNumber,
+		TerminalLabel: *terminalLabel,
+		Static:        *static,
+	}
+	if *createdAt != "" {
+		t, err := time.Parse(time.RFC3339, *createdAt)
+		if err != nil {
+			fmt.Fprintln(stderr, "khqr generate: invalid -created-at:", err)
+			return exitUsage
+		}
+		opts.CreatedAt = t
+	}
+
+	if account, err := sdk.ParseAccountID(*bankAccount); err == nil {
+		if _, ok := account.KnownBank(); !ok {
+			fmt.Fprintf(stderr, "khqr generate: warning: unknown bank %q in account %s\n", account.Bank, account)
+		}
+	}
+
+	instance := khqr.NewKHQR("")
+	qr, err := instance.CreateQRWithOptions(opts)
+	if err != nil {
+		fmt.Fprintln(stderr, "khqr generate:", err)
+		return exitError
+	}
+
+	var data []byte
+	switch *format {
+	case "text":
+		data = []byte(qr + "\n")
+	case "png":
+		data, err = instance.GenerateQRImage(qr, *size)
+	case "terminal":
+		var text string
+		width, height := terminalSize(stdout)
+		text, err = instance.RenderTerminal(qr, khqr.TerminalOptions{ANSI: *ansi, Invert: *invert, MaxWidth: width, MaxHeight: height})
+		data = []byte(text)
+	default:
+		fmt.Fprintf(stderr, "khqr generate: unknown format %q\n", *format)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "khqr generate:", err)
+		return exitError
+	}
+
+	if *output != "" {
+		err = os.WriteFile(*output, data, 0o644)
+	} else {
+		_, err = stdout.Write(data)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "khqr generate:", err)
+		return exitError
+	}
+	return exitOK
+}
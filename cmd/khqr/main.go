@@ -0,0 +1,121 @@
+// Command khqr works with Bakong KHQR payloads from the shell.
+//
+// The Bakong developer token is read from the -token flag, the BAKONG_TOKEN
+// environment variable or a .env file in the working directory.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// Exit codes returned by every subcommand.
+const (
+	exitOK          = 0
+	exitError       = 1
+	exitUsage       = 2
+	exitTimeout     = 3
+	exitInterrupted = 130
+)
+
+// command describes a single khqr subcommand.
+type command struct {
+	summary string
+	run     func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"generate": {"create a KHQR payload as text, PNG or terminal output", runGenerate},
+	"batch":    {"generate QRs in bulk from CSV or JSONL rows", runBatch},
+	"convert":  {"turn a static KHQR payload into a dynamic one for an amount", runConvert},
+	"sheet":    {"lay out printable PDF sheets of QR cards", runSheet},
+	"print":    {"print a payment slip or paid receipt on an ESC/POS printer", runPrint},
+	"decode":   {"decode a KHQR payload or QR image", runDecode},
+	"diff":     {"list the fields that differ between two KHQR payloads", runDiff},
+	"explain":  {"print every field of a KHQR payload as a tree with any spec violations", runExplain},
+	"verify":   {"check the CRC of a KHQR payload", runVerify},
+	"md5":      {"print the MD5 hash used to track a payment", runMD5},
+	"check":    {"check the payment status of one or more MD5 hashes", runCheck},
+	"deeplink": {"generate a Bakong deeplink for a KHQR payload", runDeeplink},
+	"watch":    {"poll a payment until it is paid or times out", runWatch},
+	"serve":    {"serve the KHQR operations as a JSON REST API", runServe},
+	"gateway":  {"proxy payment checks for front-end clients without exposing the token", runGateway},
+}
+
+func main() {
+	// A missing .env file is fine, the token may come from the environment or flags
+	_ = godotenv.Load()
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage(stderr)
+		return exitUsage
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "khqr: unknown command %q\n\n", args[0])
+		usage(stderr)
+		return exitUsage
+	}
+	return cmd.run(args[1:], stdin, stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: khqr <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit codes: 0 success, 1 error or invalid/unpaid result, 2 usage error,")
+	fmt.Fprintln(w, "3 watch timed out, 130 interrupted. batch exits with 1 when any row fails.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'khqr <command> -h' for the flags of a command.")
+}
+
+// newFlagSet creates a flag set that reports errors to stderr instead of exiting.
+func newFlagSet(name, usageLine string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: khqr", name, usageLine)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// tokenFlag registers the -token flag defaulting to the BAKONG_TOKEN environment variable.
+func tokenFlag(flags *flag.FlagSet) *string {
+	return flags.String("token", os.Getenv("BAKONG_TOKEN"), "Bakong developer token (default $BAKONG_TOKEN)")
+}
+
+// payloadArg returns the single positional payload, reading it from stdin when it is "-".
+func payloadArg(flags *flag.FlagSet, stdin io.Reader) (string, bool) {
+	if flags.NArg() != 1 {
+		return "", false
+	}
+	if flags.Arg(0) != "-" {
+		return flags.Arg(0), true
+	}
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", false
+	}
+	line = strings.TrimSpace(line)
+	return line, line != ""
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func runCommand(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestGenerateDecodeRoundTrip(t *testing.T) {
+	code, qr, stderr := runCommand(t, "", "generate", "-account", "your_name@wing", "-name", "Your Name", "-amount", "10000", "-bill", "TRX019283775")
+	if code != exitOK {
+		t.Fatalf("generate exited with %d: %s", code, stderr)
+	}
+
+	code, out, _ := runCommand(t, qr, "verify", "-")
+	if code != exitOK || strings.TrimSpace(out) != "valid" {
+		t.Errorf("verify exited with %d and printed %q", code, out)
+	}
+
+	code, out, _ = runCommand(t, qr, "decode", "-")
+	if code != exitOK || !strings.Contains(out, "your_name@wing") || !strings.Contains(out, "TRX019283775") {
+		t.Errorf("decode exited with %d and printed:\n%s", code, out)
+	}
+
+	code, out, _ = runCommand(t, "", "md5", strings.TrimSpace(qr))
+	if code != exitOK || len(strings.TrimSpace(out)) != 32 {
+		t.Errorf("md5 exited with %d and printed %q", code, out)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"unknown"},
+		{"generate", "-name", "Your Name"},
+		{"verify"},
+		{"watch", "-qr", "000201", "md5"},
+	} {
+		if code, _, _ := runCommand(t, "", args...); code != exitUsage {
+			t.Errorf("khqr %v exited with %d, want %d", args, code, exitUsage)
+		}
+	}
+}
+
+func TestVerifyRejectsInvalidCRC(t *testing.T) {
+	code, out, _ := runCommand(t, "", "verify", "00020101021229180014your_name@wing6304FFFF")
+	if code != exitError || strings.TrimSpace(out) != "invalid" {
+		t.Errorf("verify exited with %d and printed %q", code, out)
+	}
+}
+
+func TestBatchReportsFailedRows(t *testing.T) {
+	rows := `{"billNumber":"INV-1","amount":100}
+{"billNumber":"INV-2","amount":"abc"}
+`
+	code, out, stderr := runCommand(t, rows, "batch", "-format", "jsonl", "-account", "your_name@wing", "-name", "Your Name")
+	if code != exitError || !strings.Contains(stderr, "2 rows, 1 generated, 1 failed") {
+		t.Fatalf("batch exited with %d: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"md5"`) || !strings.Contains(lines[1], `"error"`) {
+		t.Errorf("batch printed:\n%s", out)
+	}
+}
+
+func TestDiffIgnoresTimestamp(t *testing.T) {
+	generate := func(bill string, createdAt string) string {
+		_, qr, _ := runCommand(t, "", "generate", "-account", "your_name@wing", "-name", "Your Name", "-amount", "10000", "-bill", bill, "-created-at", createdAt)
+		return strings.TrimSpace(qr)
+	}
+	first := generate("INV-1", "2024-01-01T00:00:00Z")
+	if code, out, _ := runCommand(t, first, "diff", "-", generate("INV-1", "2024-01-02T00:00:00Z")); code != exitOK || out != "" {
+		t.Errorf("diff of the same order exited with %d and printed %q", code, out)
+	}
+	code, out, _ := runCommand(t, "", "diff", first, generate("INV-2", "2024-01-01T00:00:00Z"))
+	if code != exitError || strings.TrimSpace(out) != `62.01 Bill number: "INV-1" -> "INV-2"` {
+		t.Errorf("diff exited with %d and printed %q", code, out)
+	}
+}
+
+func TestGenerateWarnsAboutUnknownBanks(t *testing.T) {
+	code, _, stderr := runCommand(t, "", "generate", "-account", "your_name@cadi", "-name", "Your Name")
+	if code != exitOK || strings.Contains(stderr, "unknown bank") {
+		t.Errorf("generate for a Bakong participant exited with %d and warned %q", code, stderr)
+	}
+
+	code, _, stderr = runCommand(t, "", "generate", "-account", "your_name@nobank", "-name", "Your Name")
+	if code != exitOK || !strings.Contains(stderr, `unknown bank "nobank"`) {
+		t.Errorf("generate for an invented bank exited with %d and warned %q", code, stderr)
+	}
+}
+
+func TestServeRefusesPublicAddressWithoutAPIKey(t *testing.T) {
+	t.Setenv("KHQR_API_KEY", "")
+	code, _, stderr := runCommand(t, "", "serve", "-addr", ":8080")
+	if code != exitUsage || !strings.Contains(stderr, "refusing") {
+		t.Errorf("serve on every interface without a key exited with %d: %s", code, stderr)
+	}
+	for addr, want := range map[string]bool{"127.0.0.1:8080": true, "localhost:8080": true, "[::1]:8080": true, ":8080": false, "0.0.0.0:8080": false, "192.168.1.10:8080": false} {
+		if got := loopbackAddr(addr); got != want {
+			t.Errorf("loopbackAddr(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestUniqueArgs(t *testing.T) {
+	got := uniqueArgs([]string{"b", "a", "b", "c", "a"})
+	if strings.Join(got, ",") != "b,a,c" {
+		t.Errorf("uniqueArgs() = %v, want [b a c]", got)
+	}
+}
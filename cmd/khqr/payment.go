@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+)
+
+func runCheck(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("check", "[-token <token>] <md5>...", stderr)
+	token := tokenFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	instance := khqr.NewKHQR(*token)
+
+	if flags.NArg() == 1 {
+		status, err := instance.CheckPayment(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(stderr, "khqr check:", err)
+			return exitError
+		}
+		fmt.Fprintln(stdout, status)
+		if status != "PAID" {
+			return exitError
+		}
+		return exitOK
+	}
+
+	md5s := uniqueArgs(flags.Args())
+	paid, err := instance.CheckBulkPayments(md5s)
+	if err != nil {
+		fmt.Fprintln(stderr, "khqr check:", err)
+		return exitError
+	}
+	paidSet := make(map[string]bool, len(paid))
+	for _, md5 := range paid {
+		paidSet[md5] = true
+	}
+	code := exitOK
+	for _, md5 := range md5s {
+		status := "PAID"
+		if !paidSet[md5] {
+			status = "UNPAID"
+			code = exitError
+		}
+		fmt.Fprintf(stdout, "%s %s\n", md5, status)
+	}
+	return code
+}
+
+// uniqueArgs returns the arguments without repeats, in the order they were first given.
+func uniqueArgs(args []string) []string {
+	seen := make(map[string]bool, len(args))
+	unique := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !seen[arg] {
+			seen[arg] = true
+			unique = append(unique, arg)
+		}
+	}
+	return unique
+}
+
+func runDeeplink(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("deeplink", "[flags] <payload> | -", stderr)
+	token := tokenFlag(flags)
+	callback := flags.String("callback", "", "URL to return to after payment")
+	appIconURL := flags.String("icon", "", "icon URL shown in the Bakong app")
+	appName := flags.String("app", "", "application name shown in the Bakong app")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	qr, ok := payloadArg(flags, stdin)
+	if !ok {
+		flags.Usage()
+		return exitUsage
+	}
+
+	deeplink, err := khqr.NewKHQR(*token).GenerateDeeplink(qr, *callback, *appIconURL, *appName)
+	if err != nil {
+		fmt.Fprintln(stderr, "khqr deeplink:", err)
+		return exitError
+	}
+	fmt.Fprintln(stdout, deeplink)
+	return exitOK
+}
+
+func runWatch(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("watch", "[flags] <md5>", stderr)
+	token := tokenFlag(flags)
+	qrPayload := flags.String("qr", "", "watch the payment of this KHQR payload instead of an MD5 hash")
+	interval := flags.Duration("interval", 5*time.Second, "time between payment checks")
+	timeout := flags.Duration("timeout", 15*time.Minute, "give up after this long, 0 waits forever")
+	quiet := flags.Bool("q", false, "do not print status changes")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	instance := khqr.NewKHQR(*token)
+
+	var md5 string
+	switch {
+	case *qrPayload != "" && flags.NArg() == 0:
+		md5 = instance.GenerateMD5(*qrPayload)
+	case *qrPayload == "" && flags.NArg() == 1:
+		md5 = flags.Arg(0)
+	default:
+		flags.Usage()
+		return exitUsage
+	}
+	if *interval <= 0 {
+		fmt.Fprintln(stderr, "khqr watch: interval must be positive")
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var timedOut <-chan time.Time
+	if *timeout > 0 {
+		timer := time.NewTimer(*timeout)
+		defer timer.Stop()
+		timedOut = timer.C
+	}
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	lastStatus := ""
+	for {
+		// A failed check, e.g. a network error or a Bakong 5xx, is retried until the timeout
+		if status, err := instance.CheckPayment(md5); err != nil {
+			fmt.Fprintln(stderr, "khqr watch:", err)
+		} else {
+			if status != lastStatus && !*quiet {
+				fmt.Fprintf(stdout, "%s %s %s\n", time.Now().Format(time.RFC3339), md5, status)
+			}
+			lastStatus = status
+			if status == "PAID" {
+				return exitOK
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timedOut:
+			fmt.Fprintln(stderr, "khqr watch: timed out waiting for payment")
+			return exitTimeout
+		case <-ctx.Done():
+			return exitInterrupted
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/escpos"
+)
+
+func runPrint(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("print", "[-receipt] [-paper 58|80] [-printer <host:port> | -o <file>] <payload|->", stderr)
+	token := tokenFlag(flags)
+	receipt := flags.Bool("receipt", false, "print the paid receipt with the transaction details instead of the payment slip")
+	paper := flags.Int("paper", 58, "roll width in millimetres: 58 or 80")
+	raster := flags.Bool("raster", false, "print the QR as an image for printers without the native QR command")
+	footer := flags.String("footer", "", "line printed at the bottom, e.g. \"Thank you!\"")
+	printer := flags.String("printer", "", "send to a network printer, e.g. 192.168.1.50:9100")
+	output := flags.String("o", "", "write the ESC/POS commands to this file or device instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	qr, ok := payloadArg(flags, stdin)
+	if !ok || (*printer != "" && *output != "") {
+		flags.Usage()
+		return exitUsage
+	}
+	instance := khqr.NewKHQR(*token)
+	opts := escpos.Options{Paper: escpos.Paper(*paper), Raster: *raster, Footer: *footer}
+
+	var tx *khqr.Transaction
+	if *receipt {
+		var err error
+		tx, err = instance.CheckTransaction(instance.GenerateMD5(qr))
+		if errors.Is(err, khqr.ErrTransactionNotFound) {
+			fmt.Fprintln(stderr, "khqr print: the QR is not paid yet")
+			return exitError
+		}
+		if err != nil {
+			fmt.Fprintln(stderr, "khqr print:", err)
+			return exitError
+		}
+	}
+
+	out := stdout
+	switch {
+	case *printer != "":
+		conn, err := net.DialTimeout("tcp", *printer, 5*time.Second)
+		if err != nil {
+			fmt.Fprintln(stderr, "khqr print:", err)
+			return exitError
+		}
+		defer conn.Close()
+		out = conn
+	case *output != "":
+		f, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+		if err != nil {
+			fmt.Fprintln(stderr, "khqr print:", err)
+			return exitError
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var err error
+	if tx != nil {
+		err = escpos.WriteReceipt(out, instance, qr, tx, opts)
+	} else {
+		err = escpos.WriteSlip(out, instance, qr, opts)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "khqr print:", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/server"
+	"github.com/chhunneng/bakong-khqr/stream"
+)
+
+func runServe(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("serve", "[-addr <host:port>] [-token <token>] [-api-key <key>]", stderr)
+	addr := flags.String("addr", "127.0.0.1:8080", "address to listen on, other than loopback only with -api-key")
+	token := tokenFlag(flags)
+	apiKey := flags.String("api-key", os.Getenv("KHQR_API_KEY"), "key clients must send as a bearer token or X-API-Key (default $KHQR_API_KEY)")
+	pollInterval := flags.Duration("poll-interval", stream.DefaultInterval, "how often /v1/status checks watched payments")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitUsage
+	}
+	if *token == "" {
+		fmt.Fprintln(stderr, "khqr serve: warning: no Bakong token, payment and deeplink endpoints will fail")
+	}
+	if *apiKey == "" && !loopbackAddr(*addr) {
+		fmt.Fprintln(stderr, "khqr serve: refusing to listen on", *addr, "without an API key, anyone who could reach it would use your Bakong token; set -api-key or listen on 127.0.0.1")
+		return exitUsage
+	}
+
+	instance := khqr.NewKHQR(*token)
+	poller := stream.NewPoller(instance, *pollInterval)
+	mux := http.NewServeMux()
+	mux.Handle("/", server.New(instance))
+	// The status stream answers like /v1/check, so it needs the API key exactly when the rest of the API does
+	var authorize stream.Authorizer = stream.AllowAll
+	if *apiKey != "" {
+		authorize = func(r *http.Request, md5 string) bool {
+			return server.HasAPIKey(r, *apiKey)
+		}
+	}
+	mux.Handle("GET /v1/status/{md5}", stream.NewHandler(poller, authorize))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go poller.Run(ctx)
+
+	var handler http.Handler = mux
+	if *apiKey != "" {
+		handler = server.RequireAPIKey(mux, *apiKey)
+	}
+	httpServer := server.NewHTTPServer(*addr, handler)
+	return serveUntilSignal(httpServer, stdout, stderr)
+}
+
+// loopbackAddr reports whether the listen address only accepts connections from this machine.
+func loopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serveUntilSignal runs the server until it fails or the process is interrupted, then shuts it down gracefully.
+func serveUntilSignal(httpServer *http.Server, stdout, stderr io.Writer) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		fmt.Fprintln(stdout, "khqr: listening on", httpServer.Addr)
+		errs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		fmt.Fprintln(stderr, "khqr:", err)
+		return exitError
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintln(stderr, "khqr:", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/pdf"
+)
+
+func runSheet(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("sheet", "(-account <id> -name <merchant> -terminals <A,B,...> | -in <payloads|->) [-o <file.pdf>] [flags]", stderr)
+	bankAccount := flags.String("account", "", "Bakong account ID of the static QRs")
+	merchantName := flags.String("name", "", "merchant name")
+	merchantCity := flags.String("city", "Phnom Penh", "merchant city")
+	currency := flags.String("currency", "KHR", "transaction currency")
+	storeLabel := flags.String("store", "", "store or branch label of every QR")
+	terminals := flags.String("terminals", "", "comma separated terminal labels, one static QR per terminal")
+	input := flags.String("in", "", "file of payloads to print instead, one per line, - for stdin")
+	pageSize := flags.String("page", "A4", "page size: A4 or Letter")
+	grid := flags.String("grid", "2x2", "cards per page as <columns>x<rows>")
+	output := flags.String("o", "", "write the PDF to this file instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if flags.NArg() != 0 || (*input == "") == (*bankAccount == "") {
+		flags.Usage()
+		return exitUsage
+	}
+	columns, rows, ok := parseGrid(*grid)
+	if !ok {
+		fmt.Fprintf(stderr, "khqr sheet: invalid grid %q, use e.g. 2x3\n", *grid)
+		return exitUsage
+	}
+
+	instance := khqr.NewKHQR("")
+	var qrs []string
+	if *input != "" {
+		in := stdin
+		if *input != "-" {
+			f, err := os.Open(*input)
+			if err != nil {
+				fmt.Fprintln(stderr, "khqr sheet:", err)
+				return exitError
+			}
+			defer f.Close()
+			in = f
+		}
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				qrs = append(qrs, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintln(stderr, "khqr sheet:", err)
+			return exitError
+		}
+	} else {
+		labels := []string{""}
+		if *terminals != "" {
+			labels = strings.Split(*terminals, ",")
+		}
+		for _, label := range labels {
+			qr, err := instance.CreateQRWithOptions(khqr.QROptions{
+				BankAccount:   *bankAccount,
+				MerchantName:  *merchantName,
+				MerchantCity:  *merchantCity,
+				Currency:      *currency,
+				StoreLabel:    *storeLabel,
+				TerminalLabel: strings.TrimSpace(label),
+				Static:        true,
+			})
+			if err != nil {
+				fmt.Fprintln(stderr, "khqr sheet:", err)
+				return exitError
+			}
+			qrs = append(qrs, qr)
+		}
+	}
+
+	out := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(stderr, "khqr sheet:", err)
+			return exitError
+		}
+		defer f.Close()
+		out = f
+	}
+	err := pdf.Write(out, instance, qrs, pdf.Options{PageSize: *pageSize, Columns: columns, Rows: rows})
+	if err != nil {
+		fmt.Fprintln(stderr, "khqr sheet:", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// parseGrid parses a grid such as "2x3" into columns and rows.
+func parseGrid(grid string) (int, int, bool) {
+	c, r, ok := strings.Cut(strings.ToLower(grid), "x")
+	if !ok {
+		return 0, 0, false
+	}
+	columns, err := strconv.Atoi(c)
+	if err != nil || columns < 1 {
+		return 0, 0, false
+	}
+	rows, err := strconv.Atoi(r)
+	if err != nil || rows < 1 {
+		return 0, 0, false
+	}
+	return columns, rows, true
+}
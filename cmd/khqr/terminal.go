@@ -0,0 +1,26 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"golang.org/x/term"
+)
+
+// terminalSize returns the columns and rows available on w, or 0 when they are unknown.
+func terminalSize(w io.Writer) (int, int) {
+	if file, ok := w.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		if width, height, err := term.GetSize(int(file.Fd())); err == nil {
+			// Leave a row for the shell prompt
+			return width, height - 1
+		}
+	}
+	// Fall back to the variables exported by most shells, e.g. when piping through less
+	width, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	height, _ := strconv.Atoi(os.Getenv("LINES"))
+	if height > 0 {
+		height--
+	}
+	return width, height
+}
@@ -0,0 +1,137 @@
+package khqr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+// ErrNotStaticQR is returned by ConvertToDynamic for a QR that already carries an amount.
+var ErrNotStaticQR = errors.New("QR is not static")
+
+// Method to turn a static QR, e.g. read from a merchant's sticker, into a dynamic QR charging the amount
+// Every merchant field of the static QR is kept as is, including tags this package does not know about;
+// the amount and currency are set, the bill number replaces any existing one or removes it when empty, the timestamp is renewed and the CRC recomputed
+// A bill number that does not fit in the additional data template with the labels of the static QR is a *ValidationError
+func (khqr *KHQR) ConvertToDynamic(qr string, amount sdk.Money, billNumber string) (string, error) {
+	decoded, err := khqr.Decode(qr)
+	if err != nil {
+		return "", err
+	}
+	if !decoded.CRCValid {
+		return "", fmt.Errorf("invalid CRC %s", decoded.CRC)
+	}
+	if decoded.PointOfInitiation != khqr.emv.StaticQR {
+		return "", fmt.Errorf("%w: point of initiation is %q", ErrNotStaticQR, decoded.PointOfInitiation)
+	}
+	currency, err := khqr.transactionCurrency.Resolve(amount.Currency)
+	if err != nil {
+		return "", err
+	}
+	if err := khqr.amount.Validate(amount); err != nil {
+		return "", err
+	}
+	if _, err := khqr.additionalDataField.BillNumberValue(billNumber); err != nil {
+		return "", err
+	}
+	// The new bill number must fit in the additional data template with the labels of the static QR
+	var additionalData []sdk.TLV
+	for _, field := range decoded.Fields {
+		if field.Tag == khqr.emv.AdditionalDataTag {
+			if additionalData, err = sdk.ParseTLV(field.Value); err != nil {
+				return "", fmt.Errorf("invalid additional data template: %w", err)
+			}
+			break
+		}
+	}
+	additionalData = setSubField(additionalData, khqr.emv.BillNumberTag, billNumber)
+	if length := templateLength(additionalData); length > maxTemplateLength {
+		err := fmt.Errorf("additional data cannot exceed %d characters with the bill number. Your input length: %d characters", maxTemplateLength, length)
+		return "", &ValidationError{Fields: []*FieldError{{Field: "billNumber", Code: CodeTooLong, Message: err.Error(), Err: err}}}
+	}
+	createdAt := khqr.clock.Now()
+
+	var qrData strings.Builder
+	e := sdk.NewEncoder(&qrData, khqr.emv)
+	// The timestamp and amount go before the currency, in the order CreateQR writes them
+	wroteTimestamp, wroteAmount, wroteAdditionalData := false, false, false
+	writeTimestamp := func(fields []sdk.TLV) {
+		fields = setSubField(fields, khqr.emv.LanguagePreference, strconv.FormatInt(createdAt.UnixMilli(), 10))
+		e.WriteTemplate(khqr.emv.TimestampTag, fields)
+		wroteTimestamp = true
+	}
+	writeAmount := func() {
+		if !wroteTimestamp {
+			writeTimestamp(nil)
+		}
+		if !wroteAmount {
+			var amountDigits [32]byte
+			e.WriteFieldBytes(khqr.emv.TransactionAmount, amount.Append(amountDigits[:0]))
+			wroteAmount = true
+		}
+	}
+	writeAdditionalData := func() {
+		if !wroteAdditionalData && len(additionalData) > 0 {
+			e.WriteTemplate(khqr.emv.AdditionalDataTag, additionalData)
+		}
+		wroteAdditionalData = true
+	}
+	for _, field := range decoded.Fields {
+		switch field.Tag {
+		case khqr.emv.PointOfInitiationMethod:
+			e.WriteField(field.Tag, khqr.emv.DynamicQR)
+		case khqr.emv.TimestampTag:
+			fields, err := sdk.ParseTLV(field.Value)
+			if err != nil {
+				return "", fmt.Errorf("invalid timestamp template: %w", err)
+			}
+			writeTimestamp(fields)
+		case khqr.emv.TransactionAmount:
+			// A static QR has no amount, skip any stray one
+		case khqr.emv.TransactionCurrency:
+			writeAmount()
+			e.WriteField(field.Tag, currency.Numeric)
+		case khqr.emv.AdditionalDataTag:
+			writeAdditionalData()
+		case khqr.emv.CRC:
+			// Static QRs always have a currency, but the amount must never be dropped
+			writeAmount()
+			writeAdditionalData()
+		default:
+			e.WriteField(field.Tag, field.Value)
+		}
+	}
+	// The encoder keeps the first error, e.g. a field of the static QR over 99 bytes,
+	// so checking the CRC write is enough to never return a payload missing a field
+	if err := e.WriteCRC(); err != nil {
+		return "", err
+	}
+
+	if khqr.store != nil {
+		if err := khqr.recordQR(qrData.String(), createdAt); err != nil {
+			return "", err
+		}
+	}
+	return qrData.String(), nil
+}
+
+// setSubField replaces the value of the sub-field with the tag, or adds it first when missing
+// An empty value removes the sub-field instead of writing an empty one
+func setSubField(fields []sdk.TLV, tag, value string) []sdk.TLV {
+	for i, field := range fields {
+		if field.Tag == tag {
+			if value == "" {
+				return append(fields[:i:i], fields[i+1:]...)
+			}
+			fields[i].Value = value
+			return fields
+		}
+	}
+	if value == "" {
+		return fields
+	}
+	return append([]sdk.TLV{{Tag: tag, Value: value}}, fields...)
+}
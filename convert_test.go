@@ -0,0 +1,99 @@
+package khqr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+func TestConvertToDynamic(t *testing.T) {
+	k := NewKHQR("")
+	k.SetClock(FixedClock(time.UnixMilli(1700000000000)))
+	opts := QROptions{
+		BankAccount:   "your_name@wing",
+		MerchantName:  "Your Name",
+		MerchantCity:  "Phnom Penh",
+		Currency:      "KHR",
+		StoreLabel:    "MShop",
+		PhoneNumber:   "85512345678",
+		TerminalLabel: "Cashier-01",
+		Static:        true,
+	}
+	static, err := k.CreateQRWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Converting gives the same QR as creating the dynamic one directly
+	k.SetClock(FixedClock(time.UnixMilli(1700000123456)))
+	amount, _ := sdk.ParseMoney("10000", "KHR")
+	dynamic, err := k.ConvertToDynamic(static, amount, "TRX019283775")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Static, opts.Money, opts.BillNumber = false, &amount, "TRX019283775"
+	want, err := k.CreateQRWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dynamic != want {
+		t.Errorf("ConvertToDynamic() = %q, want %q", dynamic, want)
+	}
+
+	if _, err := k.ConvertToDynamic(dynamic, amount, ""); !errors.Is(err, ErrNotStaticQR) {
+		t.Errorf("expected ErrNotStaticQR for a dynamic QR, got %v", err)
+	}
+
+	// Fields of other issuers are kept and a missing additional data template is added
+	foreign := "00020101021129180014your_name@wing520459995802KH5909Your Name6010Phnom Penh530384064200002km0210Phnom Penh"
+	foreign += k.crc.Value(foreign)
+	usd, _ := sdk.ParseMoney("1.5", "USD")
+	dynamic, err = k.ConvertToDynamic(foreign, usd, "INV-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, _ := k.Decode(dynamic)
+	if !decoded.CRCValid || decoded.Static || decoded.Amount != "1.5" || decoded.BillNumber != "INV-1" || decoded.MerchantCityAlternate == "" {
+		t.Errorf("unexpected conversion %q: %+v", dynamic, decoded)
+	}
+	if !strings.Contains(dynamic, "99170013170000012345654031.55303840") {
+		t.Errorf("expected the timestamp and amount before the currency in %q", dynamic)
+	}
+
+	// The bill number must fit with the labels of the static QR
+	opts = QROptions{
+		BankAccount:   "your_name@wing",
+		MerchantName:  "Your Name",
+		MerchantCity:  "Phnom Penh",
+		Currency:      "USD",
+		StoreLabel:    strings.Repeat("S", 25),
+		PhoneNumber:   "85512345678",
+		TerminalLabel: strings.Repeat("T", 25),
+		Static:        true,
+	}
+	static, err = k.CreateQRWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var validationErr *ValidationError
+	if _, err := k.ConvertToDynamic(static, usd, strings.Repeat("B", 25)); !errors.As(err, &validationErr) || validationErr.Field("billNumber") == nil {
+		t.Errorf("expected a bill number validation error for a full template, got %v", err)
+	}
+
+	// An empty bill number removes the existing one instead of writing an empty sub-field
+	opts.StoreLabel, opts.TerminalLabel, opts.BillNumber, opts.Static = "MShop", "", "INV-1", true
+	static, err = k.CreateQRWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dynamic, err = k.ConvertToDynamic(static, usd, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, _ := k.Decode(dynamic); decoded.BillNumber != "" || decoded.StoreLabel != "MShop" || strings.Contains(dynamic, "0100") {
+		t.Errorf("expected the bill number removed from %q", dynamic)
+	}
+}
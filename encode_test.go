@@ -0,0 +1,30 @@
+package khqr
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEncodeQR(t *testing.T) {
+	khqrInstance := NewKHQR("")
+	opts := templateOptions
+	opts.Amount, opts.BillNumber, opts.CreatedAt = 12.5, "TRX019283775", time.UnixMilli(1700000000000)
+
+	var buf bytes.Buffer
+	n, err := khqrInstance.EncodeQR(&buf, opts)
+	if err != nil {
+		t.Fatalf("EncodeQR() error: %v", err)
+	}
+	want, _ := khqrInstance.CreateQRWithOptions(opts)
+	if buf.String() != want || n != int64(len(want)) {
+		t.Errorf("EncodeQR() wrote %q (%d bytes), want %q", buf.String(), n, want)
+	}
+
+	// Invalid options are rejected before anything is written
+	buf.Reset()
+	opts.PhoneNumber = "123"
+	if _, err := khqrInstance.EncodeQR(&buf, opts); err == nil || buf.Len() != 0 {
+		t.Errorf("EncodeQR() with an invalid phone number wrote %q, error %v", buf.String(), err)
+	}
+}
@@ -0,0 +1,351 @@
+// Package escpos encodes payment slips and paid receipts for 58mm and 80mm
+// ESC/POS thermal printers.
+//
+// The output is the raw command stream to send to the printer, over USB,
+// serial or a raw TCP socket on port 9100, so it can be compared byte for
+// byte in tests without any hardware.
+package escpos
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"time"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+// Paper is the width of the printer roll.
+type Paper int
+
+const (
+	Paper58 Paper = 58
+	Paper80 Paper = 80
+)
+
+// columns returns the number of font A characters per line.
+func (p Paper) columns() int {
+	if p == Paper80 {
+		return 48
+	}
+	return 32
+}
+
+// dots returns the printable width in dots at 203 dpi.
+func (p Paper) dots() int {
+	if p == Paper80 {
+		return 576
+	}
+	return 384
+}
+
+// Cambodia has no daylight saving time, so receipts use a fixed UTC+7 zone by default.
+var indochinaTime = time.FixedZone("ICT", 7*60*60)
+
+// Options configures the printed layout.
+type Options struct {
+	// Paper is the roll width, Paper58 when zero.
+	Paper Paper
+	// Raster prints the QR as a bit image for printers without the native QR command.
+	Raster bool
+	// ModuleSize is the size in dots of a native QR module, 1 to 16. 6 on 58mm and 8 on 80mm when zero.
+	ModuleSize int
+	// Location is the time zone of receipt dates, Cambodia time when nil.
+	Location *time.Location
+	// Footer is printed centered at the bottom, e.g. "Thank you!".
+	Footer string
+}
+
+// withDefaults fills the zero options and checks the rest.
+func withDefaults(opts Options) (Options, error) {
+	if opts.Paper == 0 {
+		opts.Paper = Paper58
+	}
+	if opts.Paper != Paper58 && opts.Paper != Paper80 {
+		return opts, fmt.Errorf("unsupported paper width %dmm, use 58 or 80", opts.Paper)
+	}
+	if opts.ModuleSize == 0 {
+		opts.ModuleSize = 6
+		if opts.Paper == Paper80 {
+			opts.ModuleSize = 8
+		}
+	}
+	if opts.ModuleSize < 1 || opts.ModuleSize > 16 {
+		return opts, fmt.Errorf("QR module size must be between 1 and 16, got %d", opts.ModuleSize)
+	}
+	if opts.Location == nil {
+		opts.Location = indochinaTime
+	}
+	return opts, nil
+}
+
+// WriteSlip writes a payment slip for the QR: the merchant name, amount, bill number and the QR to scan.
+func WriteSlip(w io.Writer, k *khqr.KHQR, qr string, opts Options) error {
+	opts, err := withDefaults(opts)
+	if err != nil {
+		return err
+	}
+	decoded, err := k.Decode(qr)
+	if err != nil {
+		return err
+	}
+	if !decoded.CRCValid {
+		return fmt.Errorf("invalid CRC")
+	}
+
+	p := NewPrinter(opts.Paper)
+	p.Init()
+	header(p, decoded.MerchantName)
+	p.Text("Scan to pay with KHQR\n")
+	p.Rule()
+	if decoded.Amount != "" {
+		p.Align(AlignCenter)
+		p.Bold(true)
+		p.Size(2, 2)
+		p.Text(formatAmount(decoded.Amount, decoded.TransactionCurrency) + "\n")
+		p.Size(1, 1)
+		p.Bold(false)
+	}
+	p.Align(AlignLeft)
+	if decoded.BillNumber != "" {
+		p.Row("Bill", decoded.BillNumber)
+	}
+	if decoded.TerminalLabel != "" {
+		p.Row("Terminal", decoded.TerminalLabel)
+	}
+	p.Feed(1)
+	p.Align(AlignCenter)
+	if opts.Raster {
+		// Leave a quarter of the width as margin, like the native command at the default module size
+		img, err := k.QRImage(qr, opts.Paper.dots()*3/4)
+		if err != nil {
+			return err
+		}
+		p.Image(img)
+	} else {
+		if err := p.QR(qr, opts.ModuleSize); err != nil {
+			return err
+		}
+	}
+	footer(p, opts.Footer)
+	_, err = p.WriteTo(w)
+	return err
+}
+
+// WriteReceipt writes the paid receipt of the QR with the details of its transaction.
+func WriteReceipt(w io.Writer, k *khqr.KHQR, qr string, tx *khqr.Transaction, opts Options) error {
+	opts, err := withDefaults(opts)
+	if err != nil {
+		return err
+	}
+	decoded, err := k.Decode(qr)
+	if err != nil {
+		return err
+	}
+
+	p := NewPrinter(opts.Paper)
+	p.Init()
+	header(p, decoded.MerchantName)
+	p.Bold(true)
+	p.Text("PAID\n")
+	p.Bold(false)
+	p.Rule()
+	p.Align(AlignLeft)
+	amount, err := sdk.MoneyFromFloat(tx.Amount, tx.Currency)
+	if err != nil {
+		return err
+	}
+	p.Bold(true)
+	p.Row("Amount", amount.Format()+" "+amount.Currency)
+	p.Bold(false)
+	if decoded.BillNumber != "" {
+		p.Row("Bill", decoded.BillNumber)
+	}
+	p.Row("Date", tx.AcknowledgedAt().In(opts.Location).Format("2006-01-02 15:04"))
+	if tx.FromAccountID != "" {
+		p.Row("From", tx.FromAccountID)
+	}
+	if tx.Hash != "" {
+		p.Row("Ref", shorten(tx.Hash, 8))
+	}
+	p.Rule()
+	footer(p, opts.Footer)
+	_, err = p.WriteTo(w)
+	return err
+}
+
+// header prints the merchant name in double size, centered.
+func header(p *Printer, merchantName string) {
+	p.Align(AlignCenter)
+	p.Bold(true)
+	p.Size(2, 2)
+	p.Text(merchantName + "\n")
+	p.Size(1, 1)
+	p.Bold(false)
+}
+
+// footer prints the footer, then feeds the paper past the cutter and cuts.
+func footer(p *Printer, text string) {
+	if text != "" {
+		p.Align(AlignCenter)
+		p.Text(text + "\n")
+	}
+	p.Cut()
+}
+
+// formatAmount groups the digits of a decoded amount, keeping it as is when it cannot be parsed.
+func formatAmount(amount, currency string) string {
+	money, err := sdk.ParseMoney(amount, currency)
+	if err != nil {
+		return amount + " " + currency
+	}
+	return money.Format() + " " + money.Currency
+}
+
+// shorten keeps the first n characters of s.
+func shorten(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// Alignment of the following lines.
+type Alignment byte
+
+const (
+	AlignLeft   Alignment = 0
+	AlignCenter Alignment = 1
+	AlignRight  Alignment = 2
+)
+
+// Printer buffers ESC/POS commands for a roll width.
+type Printer struct {
+	buf   bytes.Buffer
+	paper Paper
+	width int // characters per line at the current size
+}
+
+// NewPrinter returns an empty Printer for the paper.
+func NewPrinter(paper Paper) *Printer {
+	return &Printer{paper: paper, width: paper.columns()}
+}
+
+// Init resets the printer to its default settings (ESC @).
+func (p *Printer) Init() {
+	p.buf.Write([]byte{0x1b, '@'})
+	p.width = p.paper.columns()
+}
+
+// Align sets the justification of the following lines (ESC a).
+func (p *Printer) Align(a Alignment) {
+	p.buf.Write([]byte{0x1b, 'a', byte(a)})
+}
+
+// Bold turns emphasized printing on or off (ESC E).
+func (p *Printer) Bold(on bool) {
+	var n byte
+	if on {
+		n = 1
+	}
+	p.buf.Write([]byte{0x1b, 'E', n})
+}
+
+// Size sets the character width and height multipliers, 1 to 8 (GS !).
+func (p *Printer) Size(width, height int) {
+	width, height = min(max(width, 1), 8), min(max(height, 1), 8)
+	p.buf.Write([]byte{0x1d, '!', byte((width-1)<<4 | (height - 1))})
+	p.width = p.paper.columns() / width
+}
+
+// Text prints the text. Characters outside ASCII are replaced with '?' since code pages differ between printers.
+func (p *Printer) Text(text string) {
+	for _, r := range text {
+		if r == '\n' || r >= ' ' && r <= '~' {
+			p.buf.WriteRune(r)
+		} else {
+			p.buf.WriteByte('?')
+		}
+	}
+}
+
+// Row prints the label on the left and the value on the right of a line, wrapping the value when it does not fit.
+func (p *Printer) Row(label, value string) {
+	gap := p.width - len(label) - len(value)
+	if gap < 1 {
+		p.Text(label + "\n")
+		gap = p.width - len(value)
+		label = ""
+	}
+	p.Text(label + strings.Repeat(" ", max(gap, 0)) + value + "\n")
+}
+
+// Rule prints a dashed line across the paper.
+func (p *Printer) Rule() {
+	p.Text(strings.Repeat("-", p.width) + "\n")
+}
+
+// Feed prints the buffer and feeds n lines (ESC d).
+func (p *Printer) Feed(n int) {
+	p.buf.Write([]byte{0x1b, 'd', byte(min(max(n, 0), 255))})
+}
+
+// QR prints the payload with the printer's own QR command (GS ( k): model 2, error correction M, the module size in dots.
+func (p *Printer) QR(data string, moduleSize int) error {
+	if len(data) > 7089 {
+		return fmt.Errorf("QR data of %d bytes is too long", len(data))
+	}
+	p.qrCommand('A', '2', 0)                          // model 2
+	p.qrCommand('C', byte(moduleSize))                // module size
+	p.qrCommand('E', '1')                             // error correction level M
+	p.qrCommand('P', append([]byte{'0'}, data...)...) // store the data
+	p.qrCommand('Q', '0')                             // print the stored symbol
+	return nil
+}
+
+// qrCommand writes a GS ( k function of the QR symbol.
+func (p *Printer) qrCommand(fn byte, params ...byte) {
+	length := len(params) + 2
+	p.buf.Write([]byte{0x1d, '(', 'k', byte(length), byte(length >> 8), '1', fn})
+	p.buf.Write(params)
+}
+
+// Image prints the image as a raster bit image (GS v 0), dark pixels black.
+// Images wider than the paper are cropped.
+func (p *Printer) Image(img image.Image) {
+	bounds := img.Bounds()
+	width := min(bounds.Dx(), p.paper.dots())
+	height := bounds.Dy()
+	rowBytes := (width + 7) / 8
+	p.buf.Write([]byte{0x1d, 'v', '0', 0, byte(rowBytes), byte(rowBytes >> 8), byte(height), byte(height >> 8)})
+	row := make([]byte, rowBytes)
+	for y := 0; y < height; y++ {
+		clear(row)
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			// Luma below half is printed
+			if 299*r+587*g+114*b < 1000*0x8000 {
+				row[x/8] |= 0x80 >> (x % 8)
+			}
+		}
+		p.buf.Write(row)
+	}
+}
+
+// Cut feeds the paper past the cutter and makes a partial cut (GS V B).
+func (p *Printer) Cut() {
+	p.buf.Write([]byte{0x1d, 'V', 'B', 3})
+}
+
+// Bytes returns the commands buffered so far.
+func (p *Printer) Bytes() []byte {
+	return p.buf.Bytes()
+}
+
+// WriteTo writes the buffered commands to w.
+func (p *Printer) WriteTo(w io.Writer) (int64, error) {
+	return p.buf.WriteTo(w)
+}
@@ -0,0 +1,79 @@
+package escpos
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+)
+
+func TestQRCommand(t *testing.T) {
+	p := NewPrinter(Paper58)
+	if err := p.QR("AB", 6); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		0x1d, '(', 'k', 4, 0, '1', 'A', '2', 0,
+		0x1d, '(', 'k', 3, 0, '1', 'C', 6,
+		0x1d, '(', 'k', 3, 0, '1', 'E', '1',
+		0x1d, '(', 'k', 5, 0, '1', 'P', '0', 'A', 'B',
+		0x1d, '(', 'k', 3, 0, '1', 'Q', '0',
+	}
+	if !bytes.Equal(p.Bytes(), want) {
+		t.Errorf("QR() = % x, want % x", p.Bytes(), want)
+	}
+}
+
+func TestSlipAndReceipt(t *testing.T) {
+	k := khqr.NewKHQR("")
+	k.SetClock(khqr.FixedClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)))
+	qr, err := k.CreateQR("user_name@abaa", "Coffee Shop", "Phnom Penh", 12500, "KHR", "", "", "INV-1", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var slip bytes.Buffer
+	if err := WriteSlip(&slip, k, qr, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range [][]byte{
+		{0x1b, '@'},
+		[]byte("Coffee Shop\n"),
+		{0x1d, '!', 0x11},
+		[]byte("12,500 KHR\n"),
+		[]byte("Bill                       INV-1\n"),
+		append([]byte{0x1d, '(', 'k', byte(len(qr) + 3), 0, '1', 'P', '0'}, qr...),
+		{0x1d, 'V', 'B', 3},
+	} {
+		if !bytes.Contains(slip.Bytes(), part) {
+			t.Errorf("slip is missing % x", part)
+		}
+	}
+
+	var raster bytes.Buffer
+	if err := WriteSlip(&raster, k, qr, Options{Paper: Paper80, Raster: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(raster.Bytes(), []byte{0x1d, 'v', '0', 0, 54, 0, 0xb0, 0x01}) {
+		t.Error("expected a 432 dot raster image on 80mm paper")
+	}
+
+	tx := &khqr.Transaction{Hash: "8a3f1c2e9d", FromAccountID: "payer@aclb", Currency: "KHR", Amount: 12500, AcknowledgedDateMs: 1704096000000}
+	var receipt bytes.Buffer
+	if err := WriteReceipt(&receipt, k, qr, tx, Options{Footer: "Thank you!"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"PAID\n",
+		"Amount                12,500 KHR\n",
+		"Date            2024-01-01 15:00\n",
+		"From                  payer@aclb\n",
+		"Ref                     8a3f1c2e\n",
+		"Thank you!\n",
+	} {
+		if !bytes.Contains(receipt.Bytes(), []byte(line)) {
+			t.Errorf("receipt is missing %q", line)
+		}
+	}
+}
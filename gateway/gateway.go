@@ -0,0 +1,247 @@
+// Package gateway lets front-end clients reach a whitelisted subset of the
+// Bakong API without ever seeing the Bakong developer token.
+//
+// Clients authenticate with their own API key in the X-API-Key header and
+// may only query payments whose md5 was claimed for them by the backend.
+package gateway
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/server"
+)
+
+// Defaults applied when a Client or Config leaves a limit unset.
+const (
+	DefaultRate  = 1.0
+	DefaultBurst = 5
+	maxBodyBytes = 64 << 10
+)
+
+// Client is a front-end application allowed to call the gateway.
+type Client struct {
+	ID     string  `json:"id"`
+	APIKey string  `json:"apiKey"`
+	Rate   float64 `json:"rate"`  // requests per second
+	Burst  int     `json:"burst"` // requests allowed at once
+}
+
+// Config configures a Gateway.
+type Config struct {
+	Clients []Client
+	// Ownership decides which client may query an md5. Defaults to an empty MemoryOwnership.
+	Ownership Ownership
+	// ClaimTTL is how long the default MemoryOwnership keeps a claim, DefaultClaimTTL when zero.
+	ClaimTTL time.Duration
+	// AdminKey enables POST /v1/claims for the backend to claim md5s for clients.
+	AdminKey string
+	// AllowedOrigins lists the browser origins allowed to call the gateway, "*" allows any.
+	AllowedOrigins []string
+}
+
+// Gateway authenticates, rate limits and forwards client requests to the KHQR server.
+type Gateway struct {
+	khqr     *khqr.KHQR
+	upstream http.Handler
+	config   Config
+	limiters map[string]*limiter
+	now      func() time.Time
+}
+
+// New initializes and returns a Gateway that injects the token of the given KHQR instance.
+func New(k *khqr.KHQR, config Config) *Gateway {
+	if config.Ownership == nil {
+		config.Ownership = NewMemoryOwnership(config.ClaimTTL)
+	}
+	g := &Gateway{
+		khqr:     k,
+		upstream: server.New(k),
+		config:   config,
+		limiters: make(map[string]*limiter, len(config.Clients)),
+		now:      time.Now,
+	}
+	for _, client := range config.Clients {
+		rate, burst := client.Rate, client.Burst
+		if rate <= 0 {
+			rate = DefaultRate
+		}
+		if burst <= 0 {
+			burst = DefaultBurst
+		}
+		g.limiters[client.ID] = &limiter{rate: rate, burst: float64(burst), tokens: float64(burst)}
+	}
+	return g
+}
+
+// ServeHTTP forwards whitelisted requests from authenticated clients.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !g.allowOrigin(w, r) {
+		writeError(w, http.StatusForbidden, "origin not allowed")
+		return
+	}
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method == http.MethodPost && r.URL.Path == "/v1/claims" {
+		g.handleClaim(w, r)
+		return
+	}
+	if r.Method != http.MethodPost || (r.URL.Path != "/v1/check" && r.URL.Path != "/v1/deeplink") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	client, ok := g.authenticate(r.Header.Get("X-API-Key"))
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid API key")
+		return
+	}
+	if !g.limiters[client.ID].allow(g.now()) {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return
+	}
+
+	// Read the body to check ownership, then hand an identical copy to the server
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	md5, err := g.requestMD5(r.URL.Path, body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if owner, ok := g.config.Ownership.Owner(md5); !ok || owner != client.ID {
+		writeError(w, http.StatusForbidden, "payment does not belong to this client")
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	g.upstream.ServeHTTP(w, r)
+}
+
+// ClaimRequest is the body of POST /v1/claims.
+type ClaimRequest struct {
+	ClientID string `json:"clientId"`
+	MD5      string `json:"md5"`
+}
+
+func (g *Gateway) handleClaim(w http.ResponseWriter, r *http.Request) {
+	if g.config.AdminKey == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Admin-Key")), []byte(g.config.AdminKey)) != 1 {
+		writeError(w, http.StatusUnauthorized, "invalid admin key")
+		return
+	}
+	claimer, ok := g.config.Ownership.(interface{ Claim(clientID, md5 string) })
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "ownership store does not accept claims")
+		return
+	}
+
+	var req ClaimRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes)).Decode(&req); err != nil || req.ClientID == "" || req.MD5 == "" {
+		writeError(w, http.StatusBadRequest, "clientId and md5 are required")
+		return
+	}
+	if _, ok := g.limiters[req.ClientID]; !ok {
+		writeError(w, http.StatusBadRequest, "unknown client")
+		return
+	}
+	claimer.Claim(req.ClientID, req.MD5)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authenticate finds the client with the given API key, comparing every key in constant time.
+func (g *Gateway) authenticate(apiKey string) (Client, bool) {
+	var found Client
+	ok := false
+	for _, client := range g.config.Clients {
+		if client.APIKey != "" && subtle.ConstantTimeCompare([]byte(apiKey), []byte(client.APIKey)) == 1 {
+			found, ok = client, true
+		}
+	}
+	return found, ok
+}
+
+// requestMD5 extracts the md5 of the payment a whitelisted request refers to.
+func (g *Gateway) requestMD5(path string, body []byte) (string, error) {
+	if path == "/v1/check" {
+		var req server.CheckRequest
+		if err := json.Unmarshal(body, &req); err != nil || req.MD5 == "" {
+			return "", errors.New("md5 is required")
+		}
+		return strings.ToLower(req.MD5), nil
+	}
+
+	var req server.DeeplinkRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.QR == "" {
+		return "", errors.New("qr is required")
+	}
+	return g.khqr.GenerateMD5(req.QR), nil
+}
+
+// allowOrigin sets the CORS headers and reports whether the request origin is allowed.
+func (g *Gateway) allowOrigin(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range g.config.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key")
+			w.Header().Add("Vary", "Origin")
+			return true
+		}
+	}
+	return false
+}
+
+// writeError writes the message in the same error format as the KHQR server.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(server.ErrorResponse{Error: message})
+}
+
+// limiter is a token bucket refilled at rate tokens per second up to burst.
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// allow takes a token from the bucket if one is available at now.
+func (l *limiter) allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
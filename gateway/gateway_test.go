@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+)
+
+const (
+	ownedMD5 = "dfcabf4598d1c405a75540a3d4ca099d"
+	otherMD5 = "5154e4f795634ff1a0ae4b48e53a6d9c"
+)
+
+func newTestGateway() (*Gateway, *MemoryOwnership) {
+	ownership := NewMemoryOwnership(0)
+	ownership.Claim("web", ownedMD5)
+	ownership.Claim("mobile", otherMD5)
+	g := New(khqr.NewKHQR(""), Config{
+		Clients: []Client{
+			{ID: "web", APIKey: "web-key", Rate: 1, Burst: 2},
+			{ID: "mobile", APIKey: "mobile-key"},
+		},
+		Ownership: ownership,
+		AdminKey:  "admin-key",
+	})
+	return g, ownership
+}
+
+func send(g *Gateway, path, apiKey, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("X-API-Key", apiKey)
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGatewayForwardsOwnedPayments(t *testing.T) {
+	g, _ := newTestGateway()
+
+	// Without a Bakong token the server fails upstream, which proves the request was forwarded
+	rec := send(g, "/v1/check", "web-key", `{"md5":"`+ownedMD5+`"}`)
+	if rec.Code != http.StatusBadGateway || !strings.Contains(strings.ToLower(rec.Body.String()), "token") {
+		t.Errorf("Owned check = %d %s, want it forwarded", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGatewayRejectsUnauthorizedRequests(t *testing.T) {
+	g, _ := newTestGateway()
+	for _, tc := range []struct {
+		path, apiKey, body string
+		status             int
+	}{
+		{"/v1/check", "", `{"md5":"` + ownedMD5 + `"}`, http.StatusUnauthorized},
+		{"/v1/check", "wrong-key", `{"md5":"` + ownedMD5 + `"}`, http.StatusUnauthorized},
+		{"/v1/check", "web-key", `{"md5":"` + otherMD5 + `"}`, http.StatusForbidden},
+		{"/v1/deeplink", "web-key", `{"qr":"00020101021229180014your_name@wing6304FFFF"}`, http.StatusForbidden},
+		{"/v1/qr", "web-key", `{}`, http.StatusNotFound},
+		{"/v1/check/bulk", "web-key", `{"md5List":["` + ownedMD5 + `"]}`, http.StatusNotFound},
+	} {
+		if rec := send(g, tc.path, tc.apiKey, tc.body); rec.Code != tc.status {
+			t.Errorf("POST %s with key %q = %d, want %d", tc.path, tc.apiKey, rec.Code, tc.status)
+		}
+	}
+}
+
+func TestGatewayRateLimitsPerClient(t *testing.T) {
+	g, _ := newTestGateway()
+	now := time.Unix(0, 0)
+	g.now = func() time.Time { return now }
+
+	body := `{"md5":"` + ownedMD5 + `"}`
+	for i := 0; i < 2; i++ {
+		if rec := send(g, "/v1/check", "web-key", body); rec.Code == http.StatusTooManyRequests {
+			t.Fatalf("Request %d within the burst was rate limited", i+1)
+		}
+	}
+	if rec := send(g, "/v1/check", "web-key", body); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Request past the burst = %d, want 429", rec.Code)
+	}
+	if rec := send(g, "/v1/check", "mobile-key", `{"md5":"`+otherMD5+`"}`); rec.Code == http.StatusTooManyRequests {
+		t.Error("Another client was rate limited by the first client's requests")
+	}
+
+	now = now.Add(time.Second)
+	if rec := send(g, "/v1/check", "web-key", body); rec.Code == http.StatusTooManyRequests {
+		t.Error("Request after the bucket refilled was rate limited")
+	}
+}
+
+func TestGatewayClaims(t *testing.T) {
+	g, ownership := newTestGateway()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/claims", strings.NewReader(`{"clientId":"web","md5":"ABC"}`))
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Claim without admin key = %d, want 401", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/claims", strings.NewReader(`{"clientId":"web","md5":"ABC"}`))
+	req.Header.Set("X-Admin-Key", "admin-key")
+	rec = httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Claim = %d %s, want 204", rec.Code, rec.Body.String())
+	}
+	if owner, ok := ownership.Owner("abc"); !ok || owner != "web" {
+		t.Errorf("Owner after claim = %q %v, want web", owner, ok)
+	}
+}
+
+func TestMemoryOwnershipExpiresClaims(t *testing.T) {
+	now := time.Unix(1714555800, 0)
+	ownership := NewMemoryOwnership(time.Hour)
+	ownership.now = func() time.Time { return now }
+	ownership.Claim("web", ownedMD5)
+	ownership.Claim("web", otherMD5)
+
+	now = now.Add(59 * time.Minute)
+	if owner, ok := ownership.Owner(ownedMD5); !ok || owner != "web" {
+		t.Errorf("Owner() before the TTL = %q, %v", owner, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := ownership.Owner(ownedMD5); ok {
+		t.Error("An expired claim still has an owner")
+	}
+	// Claiming sweeps the expired claims that were never looked up again
+	ownership.Claim("mobile", "00000000000000000000000000000000")
+	if len(ownership.owners) != 1 {
+		t.Errorf("%d claims kept after the TTL, want only the new one", len(ownership.owners))
+	}
+}
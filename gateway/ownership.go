@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultClaimTTL is how long a MemoryOwnership claim lasts when no TTL is given,
+// longer than a QR stays payable so clients can still check a late payment.
+const DefaultClaimTTL = 24 * time.Hour
+
+// Ownership records which client a payment md5 belongs to.
+type Ownership interface {
+	Owner(md5 string) (clientID string, ok bool)
+}
+
+// claim is the owner of an md5 until the claim expires.
+type claim struct {
+	clientID  string
+	expiresAt time.Time
+}
+
+// MemoryOwnership is an in-memory Ownership that the backend fills with Claim.
+// Claims expire after the TTL so a long-running gateway does not keep every md5 it ever saw.
+type MemoryOwnership struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	owners    map[string]claim
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryOwnership initializes and returns an empty MemoryOwnership whose claims last ttl,
+// or DefaultClaimTTL when ttl is not positive.
+func NewMemoryOwnership(ttl time.Duration) *MemoryOwnership {
+	if ttl <= 0 {
+		ttl = DefaultClaimTTL
+	}
+	return &MemoryOwnership{ttl: ttl, owners: make(map[string]claim), now: time.Now}
+}
+
+// Claim records that the md5 belongs to the client until the TTL elapses.
+func (m *MemoryOwnership) Claim(clientID, md5 string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	// Sweep at most once per TTL, so claiming stays constant time on average
+	if !now.Before(m.nextSweep) {
+		for key, c := range m.owners {
+			if !now.Before(c.expiresAt) {
+				delete(m.owners, key)
+			}
+		}
+		m.nextSweep = now.Add(m.ttl)
+	}
+	m.owners[strings.ToLower(md5)] = claim{clientID: clientID, expiresAt: now.Add(m.ttl)}
+}
+
+// Release forgets the owner of the md5, e.g. once the payment is paid or expired.
+func (m *MemoryOwnership) Release(md5 string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.owners, strings.ToLower(md5))
+}
+
+// Owner returns the client the md5 was claimed for, unless the claim expired.
+func (m *MemoryOwnership) Owner(md5 string) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := strings.ToLower(md5)
+	c, ok := m.owners[key]
+	if !ok {
+		return "", false
+	}
+	if !m.now().Before(c.expiresAt) {
+		delete(m.owners, key)
+		return "", false
+	}
+	return c.clientID, true
+}
@@ -2,4 +2,16 @@ module github.com/chhunneng/bakong-khqr
 
 go 1.23.3
 
-require github.com/joho/godotenv v1.5.1
+require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
+	github.com/go-pdf/fpdf v0.9.0
+	github.com/joho/godotenv v1.5.1
+	github.com/makiuchi-d/gozxing v0.1.1
+	golang.org/x/term v0.27.0
+)
+
+require (
+	golang.org/x/sys v0.28.0 // indirect
+	golang.org/x/text v0.3.7 // indirect
+	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
+)
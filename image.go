@@ -0,0 +1,57 @@
+package khqr
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+
+	"github.com/chhunneng/bakong-khqr/sdk"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// Method to read the QR code string from a PNG or JPEG image
+func (khqr *KHQR) ScanImage(r io.Reader) (string, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return "", fmt.Errorf("unable to read image: %w", err)
+	}
+
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", err
+	}
+
+	// Screenshots are rarely pure barcodes, so let the reader search harder for the symbol
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+	result, err := qrcode.NewQRCodeReader().Decode(bitmap, hints)
+	if err != nil {
+		return "", fmt.Errorf("no QR code found in image: %w", err)
+	}
+	return result.GetText(), nil
+}
+
+// Method to decode the KHQR fields from a PNG or JPEG image
+func (khqr *KHQR) DecodeImage(r io.Reader) (*sdk.DecodedQR, error) {
+	qr, err := khqr.ScanImage(r)
+	if err != nil {
+		return nil, err
+	}
+	return khqr.Decode(qr)
+}
+
+// Method to decode the KHQR fields from a PNG or JPEG image file
+func (khqr *KHQR) DecodeImageFile(path string) (*sdk.DecodedQR, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return khqr.DecodeImage(file)
+}
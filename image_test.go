@@ -0,0 +1,25 @@
+package khqr
+
+import (
+	"bytes"
+	"testing"
+)
+
+const sampleQR = "00020101021229180014your_name@wing520459995802KH5909Your Name6010Phnom Penh991700131792415013025541100000010000530311662540112TRX0192837750211855123456780305MShop0710Cashier-016304423A"
+
+func TestDecodeImage(t *testing.T) {
+	khqrInstance := NewKHQR("")
+
+	pngData, err := khqrInstance.GenerateQRImage(sampleQR, 300)
+	if err != nil {
+		t.Fatalf("Failed to render QR: %v", err)
+	}
+
+	decoded, err := khqrInstance.DecodeImage(bytes.NewReader(pngData))
+	if err != nil {
+		t.Fatalf("Failed to decode image: %v", err)
+	}
+	if decoded.BankAccount != "your_name@wing" || decoded.BillNumber != "TRX019283775" || !decoded.CRCValid {
+		t.Errorf("Unexpected decoded QR: %+v", decoded)
+	}
+}
@@ -2,12 +2,18 @@ package khqr
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/chhunneng/bakong-khqr/sdk"
+	"github.com/chhunneng/bakong-khqr/store"
 )
 
 // Define the KHQR struct
@@ -25,6 +31,13 @@ type KHQR struct {
 	additionalDataField    sdk.AdditionalDataField
 	payloadFormatIndicator sdk.PayloadFormatIndicator
 	globalUniqueIdentifier sdk.GlobalUniqueIdentifier
+	decoder                sdk.Decoder
+	comparer               sdk.Comparer
+	explainer              sdk.Explainer
+	emv                    *sdk.EMV
+	store                  store.Store
+	qrLifetime             time.Duration
+	clock                  Clock
 	bakongToken            string
 	bakongAPI              string
 }
@@ -48,61 +61,233 @@ func NewKHQR(bakongToken string) *KHQR {
 		additionalDataField:    *sdk.NewAdditionalDataField(emv),
 		payloadFormatIndicator: *sdk.NewPayloadFormatIndicator(emv),
 		globalUniqueIdentifier: *sdk.NewGlobalUniqueIdentifier(emv),
+		decoder:                *sdk.NewDecoder(emv),
+		comparer:               *sdk.NewComparer(emv),
+		explainer:              *sdk.NewExplainer(emv),
+		emv:                    emv,
+		clock:                  systemClock{},
 		bakongToken:            bakongToken,
 		bakongAPI:              "https://api-bakong.nbc.gov.kh/v1",
 	}
 }
 
+// QROptions holds the fields of a QR created by CreateQRWithOptions
+type QROptions struct {
+	BankAccount   string
+	MerchantName  string
+	MerchantCity  string
+	Amount        float64
+	Currency      string
+	StoreLabel    string
+	PhoneNumber   string
+	BillNumber    string
+	TerminalLabel string
+	Static        bool
+	// Money is the exact amount and currency, used instead of Amount and Currency when set.
+	Money *sdk.Money
+	// CreatedAt is encoded as the QR timestamp, the KHQR clock is used when it is zero.
+	// The same options and CreatedAt always produce the same QR and md5.
+	CreatedAt time.Time
+}
+
 // Method to create QR code
 func (khqr *KHQR) CreateQR(bankAccount string, merchantName string, merchantCity string, amount float64, currency string, storeLabel string, phoneNumber string, billNumber string, terminalLabel string, static bool) (string, error) {
-	qrData := khqr.payloadFormatIndicator.Value()
-	if static {
-		qrData += khqr.pointOfInitiation.Static()
-	} else {
-		qrData += khqr.pointOfInitiation.Dynamic()
+	return khqr.CreateQRWithOptions(QROptions{
+		BankAccount:   bankAccount,
+		MerchantName:  merchantName,
+		MerchantCity:  merchantCity,
+		Amount:        amount,
+		Currency:      currency,
+		StoreLabel:    storeLabel,
+		PhoneNumber:   phoneNumber,
+		BillNumber:    billNumber,
+		TerminalLabel: terminalLabel,
+		Static:        static,
+	})
+}
+
+// Method to create QR code from options, with an optional explicit creation time
+func (khqr *KHQR) CreateQRWithOptions(opts QROptions) (string, error) {
+	if opts.CreatedAt.IsZero() {
+		opts.CreatedAt = khqr.clock.Now()
 	}
-	result, err := khqr.globalUniqueIdentifier.Value(bankAccount)
-	if err != nil {
+
+	var qrData strings.Builder
+	if _, err := khqr.EncodeQR(&qrData, opts); err != nil {
 		return "", err
 	}
-	qrData += result
-	result, err = khqr.mcc.Value("")
-	if err != nil {
-		return "", err
+
+	if khqr.store != nil {
+		if err := khqr.recordQR(qrData.String(), opts.CreatedAt); err != nil {
+			return "", err
+		}
 	}
-	qrData += result
-	qrData += khqr.countryCode.Value("")
-	result, err = khqr.merchantName.Value(merchantName)
-	if err != nil {
-		return "", err
+
+	return qrData.String(), nil
+}
+
+// Method to write a QR code to w as it is encoded, without building it in memory
+// Every field is validated before the first byte is written, so an invalid QR never leaves a partial payload
+// Unlike CreateQR, the QR is not recorded in the store
+func (khqr *KHQR) EncodeQR(w io.Writer, opts QROptions) (int64, error) {
+	createdAt := opts.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = khqr.clock.Now()
 	}
-	qrData += result
-	result, err = khqr.merchantCity.Value(merchantCity)
+
+	v, err := khqr.validate(opts)
 	if err != nil {
-		return "", err
+		return 0, err
 	}
-	qrData += result
-	qrData += khqr.timestamp.Value()
+
+	e := sdk.NewEncoder(w, khqr.emv)
+	khqr.encodeMerchant(e, opts)
+	var payment [256]byte
+	e.WriteRawBytes(khqr.appendPayment(payment[:0], v, v.additionalData, opts.Static, createdAt))
+	// Every write after a failed one returns the same error, so only the last needs checking
+	err = e.WriteCRC()
+	return e.Written(), err
+}
+
+// encodeMerchant writes the fields that come before the timestamp:
+// payload format, point of initiation, account, category code, country, merchant name and city
+func (khqr *KHQR) encodeMerchant(e *sdk.Encoder, opts QROptions) {
+	e.WriteField(khqr.payloadFormatIndicator.PayloadFormatIndicator, khqr.payloadFormatIndicator.DefaultPayloadFormatIndicator)
+	if opts.Static {
+		e.WriteRaw(khqr.pointOfInitiation.Static())
+	} else {
+		e.WriteRaw(khqr.pointOfInitiation.Dynamic())
+	}
+	e.WriteTemplate(khqr.globalUniqueIdentifier.MerchantAccountInformationIndividual, []sdk.TLV{
+		{Tag: khqr.globalUniqueIdentifier.PayloadFormatIndicator, Value: opts.BankAccount},
+	})
+	e.WriteField(khqr.mcc.MerchantCategoryCodeTag, khqr.mcc.DefaultMerchantCategoryCode)
+	e.WriteField(khqr.countryCode.CountryCodeTag, khqr.countryCode.DefaultCountryCode)
+	e.WriteField(khqr.merchantName.MerchantNameTag, opts.MerchantName)
+	e.WriteField(khqr.merchantCity.MerchantCityTag, opts.MerchantCity)
+}
+
+// appendPayment appends the fields that change between QRs of a merchant, up to the CRC:
+// timestamp, amount, currency and additional data. The values must already be validated.
+// The additional data is a separate parameter so a caller's stack array of sub-fields does not escape with the amount.
+func (khqr *KHQR) appendPayment(buf []byte, v validatedQR, additionalData []sdk.TLV, static bool, createdAt time.Time) []byte {
+	var millis [20]byte
+	timestamp := strconv.AppendInt(millis[:0], createdAt.UnixMilli(), 10)
+	buf = sdk.AppendHeader(buf, khqr.timestamp.TimestampTag, 4+len(timestamp))
+	buf = sdk.AppendFieldBytes(buf, khqr.timestamp.LanguagePreference, timestamp)
 	if !static {
-		result, err = khqr.amount.Value(amount)
-		if err != nil {
-			return "", err
+		var amountDigits [32]byte
+		buf = sdk.AppendFieldBytes(buf, khqr.amount.TransactionAmount, v.money.Append(amountDigits[:0]))
+	}
+	buf = sdk.AppendField(buf, khqr.transactionCurrency.TransactionCurrency, v.currency.Numeric)
+	return sdk.AppendTemplate(buf, khqr.additionalDataField.AdditionalDataTag, additionalData)
+}
+
+// money returns the exact amount of the options, converting the float Amount when Money is not set
+func (khqr *KHQR) money(opts QROptions) (sdk.Money, error) {
+	if opts.Money != nil {
+		return *opts.Money, nil
+	}
+	money, err := sdk.MoneyFromFloat(opts.Amount, opts.Currency)
+	if err != nil {
+		return sdk.Money{}, fmt.Errorf("invalid amount %v %s: %w", opts.Amount, opts.Currency, err)
+	}
+	return money, nil
+}
+
+// Method to allow generating QRs in other ISO 4217 currencies, e.g. for cross-border payments
+// Only USD and KHR are allowed by default, calling it without codes restores that default
+func (khqr *KHQR) SetAllowedCurrencies(codes ...string) error {
+	if len(codes) == 0 {
+		khqr.transactionCurrency.Allowed = sdk.NewTransactionCurrency(khqr.emv).Allowed
+		return nil
+	}
+	allowed := make([]string, 0, len(codes))
+	for _, code := range codes {
+		currency, ok := sdk.LookupCurrency(code)
+		if !ok {
+			return fmt.Errorf("unknown currency code '%s'", code)
 		}
-		qrData += result
+		allowed = append(allowed, currency.Code)
 	}
-	result, err = khqr.transactionCurrency.Value(currency)
+	khqr.transactionCurrency.Allowed = allowed
+	return nil
+}
+
+// Method to record every QR created from now on in a store
+// Dynamic QRs expire after lifetime, a zero lifetime records them without expiry
+func (khqr *KHQR) SetStore(s store.Store, lifetime time.Duration) {
+	khqr.store = s
+	khqr.qrLifetime = lifetime
+}
+
+// recordQR saves a newly created QR in the store as unpaid
+// Creating the same QR again, e.g. with a fixed clock, keeps the stored payment and its status
+func (khqr *KHQR) recordQR(qr string, createdAt time.Time) error {
+	decoded, err := khqr.Decode(qr)
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	createdAt = createdAt.UTC()
+	payment := store.Payment{
+		MD5:        khqr.GenerateMD5(qr),
+		QR:         qr,
+		Amount:     decoded.Amount,
+		Currency:   decoded.TransactionCurrency,
+		BillNumber: decoded.BillNumber,
+		Status:     store.StatusUnpaid,
+		CreatedAt:  createdAt,
+	}
+	if !decoded.Static && khqr.qrLifetime > 0 {
+		payment.ExpiresAt = createdAt.Add(khqr.qrLifetime)
+	}
+	ctx := context.Background()
+	err = khqr.store.Save(ctx, payment)
+	if errors.Is(err, store.ErrDuplicate) {
+		// Only a different QR with the same md5 is a real conflict
+		if existing, getErr := khqr.store.Get(ctx, payment.MD5); getErr == nil && existing.QR == qr {
+			return nil
+		}
 	}
-	qrData += result
-	result, err = khqr.additionalDataField.Value(storeLabel, phoneNumber, billNumber, terminalLabel)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("unable to record QR: %w", err)
 	}
-	qrData += result
-	qrData += khqr.crc.Value(qrData)
+	return nil
+}
+
+// Method to decode a QR code string into its fields
+func (khqr *KHQR) Decode(qr string) (*sdk.DecodedQR, error) {
+	return khqr.decoder.Decode(qr)
+}
 
-	return qrData, nil
+// Method to break a QR code string down into an annotated tree of its data objects, to see what is wrong with it
+func (khqr *KHQR) Explain(qr string) *sdk.Explanation {
+	return khqr.explainer.Explain(qr)
+}
+
+// Method to check whether two QR code strings carry the same payment, ignoring the timestamp and CRC
+func (khqr *KHQR) Equal(a, b string) (bool, error) {
+	diffs, err := khqr.Diff(a, b, sdk.DiffOptions{})
+	return len(diffs) == 0 && err == nil, err
+}
+
+// Method to list the fields that differ between two QR code strings
+func (khqr *KHQR) Diff(a, b string, opts sdk.DiffOptions) ([]sdk.FieldDiff, error) {
+	left, err := khqr.Decode(a)
+	if err != nil {
+		return nil, fmt.Errorf("first QR: %w", err)
+	}
+	right, err := khqr.Decode(b)
+	if err != nil {
+		return nil, fmt.Errorf("second QR: %w", err)
+	}
+	return khqr.comparer.Diff(left, right, opts), nil
+}
+
+// Method to verify the CRC of a QR code string
+func (khqr *KHQR) Verify(qr string) bool {
+	return khqr.crc.Verify(qr)
 }
 
 // Method to generate deep link
@@ -206,14 +391,18 @@ func (khqr *KHQR) CheckPayment(md5 string) (string, error) {
 		return "", err
 	}
 
-	var response map[string]interface{}
+	// JSON numbers decode as float64 into an interface, so the codes are read into typed fields
+	var response struct {
+		ResponseCode int  `json:"responseCode"`
+		ErrorCode    *int `json:"errorCode"`
+	}
 	if err := json.Unmarshal(body, &response); err != nil {
 		return "", err
 	}
 
-	if response["responseCode"] == 0 {
+	if response.ResponseCode == 0 {
 		return "PAID", nil
-	} else if response["responseCode"] == 1 && response["errorCode"] == 6 {
+	} else if response.ResponseCode == 1 && response.ErrorCode != nil && *response.ErrorCode == 6 {
 		return "", fmt.Errorf("our developer token is either incorrect or expired, please renew it through Bakong Developer")
 	}
 
@@ -251,20 +440,27 @@ func (khqr *KHQR) CheckBulkPayments(md5List []string) ([]string, error) {
 		return nil, err
 	}
 
-	var response map[string]interface{}
+	var response struct {
+		ResponseCode int  `json:"responseCode"`
+		ErrorCode    *int `json:"errorCode"`
+		Data         []struct {
+			MD5    string `json:"md5"`
+			Status string `json:"status"`
+		} `json:"data"`
+	}
 	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, err
 	}
 
-	if response["responseCode"] == 0 {
+	if response.ResponseCode == 0 {
 		var paidList []string
-		for _, data := range response["data"].([]interface{}) {
-			if data.(map[string]interface{})["status"] == "SUCCESS" {
-				paidList = append(paidList, data.(map[string]interface{})["md5"].(string))
+		for _, data := range response.Data {
+			if data.Status == "SUCCESS" {
+				paidList = append(paidList, data.MD5)
 			}
 		}
 		return paidList, nil
-	} else if response["responseCode"] == 1 && response["errorCode"] == 6 {
+	} else if response.ResponseCode == 1 && response.ErrorCode != nil && *response.ErrorCode == 6 {
 		return nil, fmt.Errorf("your developer token is either incorrect or expired. please renew it through Bakong Developer")
 	}
 
@@ -0,0 +1,58 @@
+package khqr
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckPayment(t *testing.T) {
+	const paid, unpaid = "d41d8cd98f00b204e9800998ecf8427e", "00000000000000000000000000000000"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/check_transaction_by_md5":
+			var body struct{ MD5 string }
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.MD5 == paid {
+				w.Write([]byte(`{"responseCode":0,"responseMessage":"Success","errorCode":null,"data":{"hash":"8a3f1c2e9d"}}`))
+				return
+			}
+			w.Write([]byte(`{"responseCode":1,"responseMessage":"Transaction could not be found. Please check and try again.","errorCode":1,"data":null}`))
+		case "/check_transaction_by_md5_list":
+			w.Write([]byte(`{"responseCode":0,"responseMessage":"Success","errorCode":null,"data":[{"md5":"` + paid + `","status":"SUCCESS"},{"md5":"` + unpaid + `","status":"NOT_FOUND"}]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	k := NewKHQR("token")
+	k.bakongAPI = server.URL
+	if status, err := k.CheckPayment(paid); err != nil || status != "PAID" {
+		t.Errorf("CheckPayment(paid) = %q, %v, want PAID", status, err)
+	}
+	if status, err := k.CheckPayment(unpaid); err != nil || status != "UNPAID" {
+		t.Errorf("CheckPayment(unpaid) = %q, %v, want UNPAID", status, err)
+	}
+	paidList, err := k.CheckBulkPayments([]string{paid, unpaid})
+	if err != nil || len(paidList) != 1 || paidList[0] != paid {
+		t.Errorf("CheckBulkPayments() = %v, %v, want [%s]", paidList, err, paid)
+	}
+}
+
+func TestCheckPaymentExpiredToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"responseCode":1,"responseMessage":"Unauthorized","errorCode":6,"data":null}`))
+	}))
+	defer server.Close()
+
+	k := NewKHQR("expired")
+	k.bakongAPI = server.URL
+	if _, err := k.CheckPayment("d41d8cd98f00b204e9800998ecf8427e"); err == nil {
+		t.Error("CheckPayment accepted an expired token")
+	}
+	if _, err := k.CheckBulkPayments([]string{"d41d8cd98f00b204e9800998ecf8427e"}); err == nil {
+		t.Error("CheckBulkPayments accepted an expired token")
+	}
+}
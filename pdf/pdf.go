@@ -0,0 +1,231 @@
+// Package pdf lays out KHQR cards on printable pages, e.g. standees and
+// table stickers for every terminal of a branch.
+//
+// Each card follows the KHQR look: a red header, the merchant name and amount,
+// a dashed divider, the QR code and the terminal or store label below it.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/sdk"
+	"github.com/go-pdf/fpdf"
+)
+
+// Page sizes in millimetres.
+var pageSizes = map[string]fpdf.SizeType{
+	"A4":     {Wd: 210, Ht: 297},
+	"Letter": {Wd: 215.9, Ht: 279.4},
+}
+
+// Card proportions and colours.
+const (
+	cardAspect  = 29.0 / 20.0 // height over width of a KHQR card
+	headerRatio = 0.12        // header height over card height
+	pointsPerMM = 72 / 25.4
+	imagePixels = 600
+)
+
+var khqrRed = [3]int{225, 35, 46}
+
+// Options configures the page layout.
+type Options struct {
+	// PageSize is "A4" or "Letter", A4 when empty.
+	PageSize string
+	// Columns and Rows set the grid of cards on each page, 2 by 2 when zero.
+	Columns int
+	Rows    int
+	// Margin around the page and Gap between cards, in millimetres. 10 and 6 when zero.
+	Margin float64
+	Gap    float64
+}
+
+// card is what is printed for one QR.
+type card struct {
+	qr       string
+	merchant string
+	amount   string
+	label    string
+}
+
+// Write lays out a card for every QR on as many pages as needed and writes the PDF to w.
+// Captions are taken from the payload: merchant name, amount and terminal label, or store label when there is none.
+func Write(w io.Writer, k *khqr.KHQR, qrs []string, opts Options) error {
+	if len(qrs) == 0 {
+		return fmt.Errorf("no QR codes to print")
+	}
+	opts, err := withDefaults(opts)
+	if err != nil {
+		return err
+	}
+
+	cards := make([]card, len(qrs))
+	for i, qr := range qrs {
+		if cards[i], err = newCard(k, qr); err != nil {
+			return fmt.Errorf("QR %d: %w", i+1, err)
+		}
+	}
+
+	size := pageSizes[opts.PageSize]
+	doc := fpdf.NewCustom(&fpdf.InitType{UnitStr: "mm", Size: size})
+	doc.SetMargins(0, 0, 0)
+	doc.SetAutoPageBreak(false, 0)
+	doc.SetCreator("bakong-khqr", true)
+	translate := doc.UnicodeTranslatorFromDescriptor("")
+
+	cellWidth := (size.Wd - 2*opts.Margin - float64(opts.Columns-1)*opts.Gap) / float64(opts.Columns)
+	cellHeight := (size.Ht - 2*opts.Margin - float64(opts.Rows-1)*opts.Gap) / float64(opts.Rows)
+	width := min(cellWidth, cellHeight/cardAspect)
+	height := width * cardAspect
+	if width < 30 {
+		return fmt.Errorf("a %dx%d grid leaves %.0fmm wide cards, use fewer columns or rows", opts.Columns, opts.Rows, width)
+	}
+
+	perPage := opts.Columns * opts.Rows
+	for i, c := range cards {
+		if i%perPage == 0 {
+			doc.AddPage()
+		}
+		column, row := i%perPage%opts.Columns, i%perPage/opts.Columns
+		// Center the card in its grid cell
+		x := opts.Margin + float64(column)*(cellWidth+opts.Gap) + (cellWidth-width)/2
+		y := opts.Margin + float64(row)*(cellHeight+opts.Gap) + (cellHeight-height)/2
+		if err := drawCard(doc, k, c, x, y, width, height, translate, i); err != nil {
+			return fmt.Errorf("QR %d: %w", i+1, err)
+		}
+	}
+	return doc.Output(w)
+}
+
+// withDefaults fills the zero options and checks the rest.
+func withDefaults(opts Options) (Options, error) {
+	if opts.PageSize == "" {
+		opts.PageSize = "A4"
+	}
+	if _, ok := pageSizes[opts.PageSize]; !ok {
+		return opts, fmt.Errorf("unknown page size %q, use A4 or Letter", opts.PageSize)
+	}
+	if opts.Columns == 0 {
+		opts.Columns = 2
+	}
+	if opts.Rows == 0 {
+		opts.Rows = 2
+	}
+	if opts.Columns < 0 || opts.Rows < 0 {
+		return opts, fmt.Errorf("invalid grid %dx%d", opts.Columns, opts.Rows)
+	}
+	if opts.Margin == 0 {
+		opts.Margin = 10
+	}
+	if opts.Gap == 0 {
+		opts.Gap = 6
+	}
+	return opts, nil
+}
+
+// newCard takes the captions of a card from the decoded payload.
+func newCard(k *khqr.KHQR, qr string) (card, error) {
+	decoded, err := k.Decode(qr)
+	if err != nil {
+		return card{}, err
+	}
+	if !decoded.CRCValid {
+		return card{}, fmt.Errorf("invalid CRC")
+	}
+	c := card{qr: qr, merchant: decoded.MerchantName, label: decoded.TerminalLabel}
+	if c.label == "" {
+		c.label = decoded.StoreLabel
+	}
+	if decoded.Amount != "" {
+		c.amount = decoded.Amount + " " + decoded.TransactionCurrency
+		if money, err := sdk.ParseMoney(decoded.Amount, decoded.TransactionCurrency); err == nil {
+			c.amount = money.Format() + " " + money.Currency
+		}
+	}
+	return c, nil
+}
+
+// drawCard draws one card with its top left corner at x, y.
+func drawCard(doc *fpdf.Fpdf, k *khqr.KHQR, c card, x, y, width, height float64, translate func(string) string, index int) error {
+	headerHeight := height * headerRatio
+	padding := width * 0.09
+	radius := width * 0.05
+
+	// Card outline, also the cutting line
+	doc.SetDrawColor(200, 200, 200)
+	doc.SetLineWidth(0.2)
+	doc.RoundedRect(x, y, width, height, radius, "1234", "D")
+
+	// Red header with the KHQR wordmark and the folded corner
+	doc.SetFillColor(khqrRed[0], khqrRed[1], khqrRed[2])
+	doc.RoundedRect(x, y, width, headerHeight, radius, "12", "F")
+	doc.Polygon([]fpdf.PointType{
+		{X: x + width - headerHeight/3, Y: y + headerHeight},
+		{X: x + width, Y: y + headerHeight},
+		{X: x + width, Y: y + headerHeight*4/3},
+	}, "F")
+	doc.SetTextColor(255, 255, 255)
+	doc.SetFont("Helvetica", "B", headerHeight*0.45*pointsPerMM)
+	doc.SetXY(x, y)
+	doc.CellFormat(width, headerHeight, "KHQR", "", 0, "CM", false, 0, "")
+
+	// Merchant name and amount
+	doc.SetTextColor(31, 31, 31)
+	cursor := y + headerHeight + height*0.035
+	nameSize := height * 0.035
+	doc.SetFont("Helvetica", "", nameSize*pointsPerMM)
+	doc.SetXY(x+padding, cursor)
+	doc.CellFormat(width-2*padding, nameSize*1.2, translate(fit(doc, c.merchant, width-2*padding)), "", 0, "LM", false, 0, "")
+	cursor += nameSize * 1.3
+	amountSize := height * 0.055
+	if c.amount != "" {
+		doc.SetFont("Helvetica", "B", amountSize*pointsPerMM)
+		doc.SetXY(x+padding, cursor)
+		doc.CellFormat(width-2*padding, amountSize*1.2, translate(c.amount), "", 0, "LM", false, 0, "")
+	}
+	cursor += amountSize*1.2 + height*0.02
+
+	// Dashed divider running to the card edges
+	doc.SetDrawColor(160, 160, 160)
+	doc.SetDashPattern([]float64{1, 1}, 0)
+	doc.Line(x, cursor, x+width, cursor)
+	doc.SetDashPattern(nil, 0)
+	cursor += height * 0.04
+
+	// QR code, as large as the space above the label allows
+	labelSize := height * 0.04
+	qrSide := min(width-2*padding, y+height-cursor-labelSize*2.5)
+	png, err := k.GenerateQRImage(c.qr, imagePixels)
+	if err != nil {
+		return err
+	}
+	name := "qr" + strconv.Itoa(index)
+	doc.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+	doc.ImageOptions(name, x+(width-qrSide)/2, cursor, qrSide, qrSide, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	cursor += qrSide
+
+	// Terminal or store label
+	if c.label != "" {
+		doc.SetFont("Helvetica", "B", labelSize*pointsPerMM)
+		doc.SetTextColor(102, 102, 102)
+		doc.SetXY(x, cursor+labelSize*0.4)
+		doc.CellFormat(width, labelSize*1.2, translate(fit(doc, c.label, width-2*padding)), "", 0, "CM", false, 0, "")
+	}
+	return doc.Error()
+}
+
+// fit shortens text with an ellipsis until it fits in width at the current font.
+func fit(doc *fpdf.Fpdf, text string, width float64) string {
+	if doc.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 1 && doc.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
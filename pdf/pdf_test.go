@@ -0,0 +1,46 @@
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+)
+
+func TestWriteLaysOutCardsOnPages(t *testing.T) {
+	k := khqr.NewKHQR("")
+	var qrs []string
+	for _, terminal := range []string{"T1", "T2", "T3", "T4", "T5"} {
+		qr, err := k.CreateQRWithOptions(khqr.QROptions{
+			BankAccount:   "user_name@abaa",
+			MerchantName:  "Coffee Shop",
+			MerchantCity:  "Phnom Penh",
+			Currency:      "KHR",
+			TerminalLabel: terminal,
+			Static:        true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		qrs = append(qrs, qr)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, k, qrs, Options{Columns: 2, Rows: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Fatalf("output does not start with a PDF header: %q", buf.Bytes()[:8])
+	}
+	if !strings.Contains(buf.String(), "/Count 2") {
+		t.Error("expected 5 cards on a 2x2 grid to take 2 pages")
+	}
+
+	if err := Write(&buf, k, []string{"not a payload"}, Options{}); err == nil {
+		t.Error("expected an invalid payload to fail")
+	}
+	if err := Write(&buf, k, qrs, Options{PageSize: "A3"}); err == nil {
+		t.Error("expected an unknown page size to fail")
+	}
+}
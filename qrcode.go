@@ -0,0 +1,57 @@
+package khqr
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// Method to render a QR code string as a square image of the given size in pixels
+func (khqr *KHQR) QRImage(qr string, size int) (image.Image, error) {
+	if qr == "" {
+		return nil, fmt.Errorf("QR code string cannot be empty")
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("image size must be positive, got %d", size)
+	}
+
+	// Medium error correction keeps the symbol small while tolerating printed wear
+	hints := map[gozxing.EncodeHintType]interface{}{
+		gozxing.EncodeHintType_ERROR_CORRECTION: "M",
+	}
+	matrix, err := qrcode.NewQRCodeWriter().Encode(qr, gozxing.BarcodeFormat_QR_CODE, size, size, hints)
+	if err != nil {
+		return nil, err
+	}
+
+	img := image.NewGray(image.Rect(0, 0, matrix.GetWidth(), matrix.GetHeight()))
+	for y := 0; y < matrix.GetHeight(); y++ {
+		for x := 0; x < matrix.GetWidth(); x++ {
+			if matrix.Get(x, y) {
+				img.SetGray(x, y, color.Gray{Y: 0})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img, nil
+}
+
+// Method to render a QR code string as PNG image data
+func (khqr *KHQR) GenerateQRImage(qr string, size int) ([]byte, error) {
+	img, err := khqr.QRImage(qr, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
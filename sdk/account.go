@@ -0,0 +1,110 @@
+package sdk
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed banks.csv
+var banksCSV string
+
+// ErrInvalidAccountID is returned for a Bakong account ID that is not of the form name@bank.
+var ErrInvalidAccountID = errors.New("invalid Bakong account ID")
+
+// Bank is a Bakong participant institution, identified by its account ID suffix.
+type Bank struct {
+	Code string // account ID suffix, e.g. "wing"
+	Name string // display name, e.g. "Wing Bank"
+}
+
+// banks is the participant registry, seeded from the embedded banks.csv.
+// A bank suffix is the first four letters of the participant's SWIFT BIC, e.g. aclb for ACLBKHPP.
+var (
+	banksMu sync.RWMutex
+	banks   = make(map[string]Bank)
+)
+
+func init() {
+	records, err := csv.NewReader(strings.NewReader(banksCSV)).ReadAll()
+	if err != nil {
+		panic("sdk: invalid banks.csv: " + err.Error())
+	}
+	for _, record := range records[1:] {
+		banks[record[0]] = Bank{Code: record[0], Name: record[1]}
+	}
+}
+
+// RegisterBank adds or replaces a participant in the registry, e.g. one that joined Bakong after this release.
+func RegisterBank(bank Bank) {
+	banksMu.Lock()
+	defer banksMu.Unlock()
+	bank.Code = strings.ToLower(bank.Code)
+	banks[bank.Code] = bank
+}
+
+// LookupBank returns the participant with the account ID suffix.
+func LookupBank(code string) (Bank, bool) {
+	banksMu.RLock()
+	defer banksMu.RUnlock()
+	bank, ok := banks[strings.ToLower(code)]
+	return bank, ok
+}
+
+// Banks returns every registered participant sorted by code.
+func Banks() []Bank {
+	banksMu.RLock()
+	defer banksMu.RUnlock()
+	list := make([]Bank, 0, len(banks))
+	for _, bank := range banks {
+		list = append(list, bank)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// AccountID is a parsed Bakong account ID such as "your_name@wing".
+type AccountID struct {
+	Name string
+	Bank string // account ID suffix, lowercased
+}
+
+// ParseAccountID checks that the ID is of the form name@bank.
+// The name may contain letters, digits, '.', '_' and '-', the bank suffix letters and digits.
+// Unknown bank suffixes are accepted, use KnownBank to warn about them.
+func ParseAccountID(id string) (AccountID, error) {
+	name, bank, ok := strings.Cut(id, "@")
+	if !ok || name == "" || bank == "" {
+		return AccountID{}, fmt.Errorf("%w %q, expected name@bank", ErrInvalidAccountID, id)
+	}
+	for _, r := range name {
+		if !isAlphanumeric(r) && r != '.' && r != '_' && r != '-' {
+			return AccountID{}, fmt.Errorf("%w %q, name contains %q", ErrInvalidAccountID, id, r)
+		}
+	}
+	for _, r := range bank {
+		if !isAlphanumeric(r) {
+			return AccountID{}, fmt.Errorf("%w %q, bank suffix contains %q", ErrInvalidAccountID, id, r)
+		}
+	}
+	return AccountID{Name: name, Bank: strings.ToLower(bank)}, nil
+}
+
+// String returns the account ID as name@bank.
+func (a AccountID) String() string {
+	return a.Name + "@" + a.Bank
+}
+
+// KnownBank returns the participant of the account ID suffix, if it is registered.
+func (a AccountID) KnownBank() (Bank, bool) {
+	return LookupBank(a.Bank)
+}
+
+// isAlphanumeric reports whether r is an ASCII letter or digit.
+func isAlphanumeric(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
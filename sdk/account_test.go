@@ -0,0 +1,47 @@
+package sdk
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseAccountID(t *testing.T) {
+	account, err := ParseAccountID("your_name@WING")
+	if err != nil || account.Name != "your_name" || account.Bank != "wing" {
+		t.Fatalf("ParseAccountID() = %+v, %v", account, err)
+	}
+	if bank, ok := account.KnownBank(); !ok || bank.Name != "Wing Bank" {
+		t.Errorf("KnownBank() = %+v, %v", bank, ok)
+	}
+
+	for _, id := range []string{"foo", "@wing", "foo@", "foo bar@wing", "foo@wi.ng", "a@b@c"} {
+		if _, err := ParseAccountID(id); !errors.Is(err, ErrInvalidAccountID) {
+			t.Errorf("ParseAccountID(%q) error = %v, want ErrInvalidAccountID", id, err)
+		}
+	}
+	if _, err := NewGlobalUniqueIdentifier(NewEMV()).Value("foo"); !errors.Is(err, ErrInvalidAccountID) {
+		t.Errorf("Value(foo) error = %v, want ErrInvalidAccountID", err)
+	}
+}
+
+func TestBankRegistry(t *testing.T) {
+	for _, code := range []string{"abaa", "aclb", "wing"} {
+		if _, ok := LookupBank(code); !ok {
+			t.Errorf("LookupBank(%q) not found", code)
+		}
+	}
+
+	account, _ := ParseAccountID("shop@newb")
+	if _, ok := account.KnownBank(); ok {
+		t.Fatal("newb is registered before RegisterBank")
+	}
+	RegisterBank(Bank{Code: "NEWB", Name: "New Bank"})
+	if bank, ok := account.KnownBank(); !ok || bank.Name != "New Bank" {
+		t.Errorf("KnownBank() after RegisterBank = %+v, %v", bank, ok)
+	}
+
+	decoded, err := NewDecoder(NewEMV()).Decode(dynamicQR)
+	if err != nil || decoded.BankCode != "wing" || decoded.BankName != "Wing Bank" {
+		t.Errorf("Decoded bank %q %q, %v", decoded.BankCode, decoded.BankName, err)
+	}
+}
@@ -54,8 +54,15 @@ func (a *AdditionalDataField) StoreLabelValue(storeLabel string) (string, error)
 	return a.formatValue(a.StoreLabelTag, storeLabel), nil
 }
 
-// PhoneNumberValue formats and validates the phone number value.
+// PhoneNumberValue validates the phone number and formats it in the canonical 855 form.
 func (a *AdditionalDataField) PhoneNumberValue(phoneNumber string) (string, error) {
+	if phoneNumber != "" {
+		parsed, err := ParsePhoneNumber(phoneNumber)
+		if err != nil {
+			return "", err
+		}
+		phoneNumber = parsed.String()
+	}
 	if err := a.validateLength(phoneNumber, a.MobileNumberLength, "Phone number"); err != nil {
 		return "", err
 	}
@@ -78,26 +85,46 @@ func (a *AdditionalDataField) TerminalLabelValue(terminalLabel string) (string,
 	return a.formatValue(a.TerminalLabelTag, terminalLabel), nil
 }
 
-// Value combines all formatted values into a single string with a length prefix.
-func (a *AdditionalDataField) Value(storeLabel, phoneNumber, billNumber, terminalLabel string) (string, error) {
-	storeLabelValue, err := a.StoreLabelValue(storeLabel)
-	if err != nil {
-		return "", err
+// Fields validates the values and returns them as sub-fields in encoding order, with the phone number in canonical form.
+func (a *AdditionalDataField) Fields(storeLabel, phoneNumber, billNumber, terminalLabel string) ([]TLV, error) {
+	if err := a.validateLength(storeLabel, a.StoreLabelLength, "Store label"); err != nil {
+		return nil, err
 	}
-	phoneNumberValue, err := a.PhoneNumberValue(phoneNumber)
-	if err != nil {
-		return "", err
+	if phoneNumber != "" {
+		parsed, err := ParsePhoneNumber(phoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		phoneNumber = parsed.String()
 	}
-	billNumberValue, err := a.BillNumberValue(billNumber)
-	if err != nil {
-		return "", err
+	if err := a.validateLength(phoneNumber, a.MobileNumberLength, "Phone number"); err != nil {
+		return nil, err
+	}
+	if err := a.validateLength(billNumber, a.BillNumberLength, "Bill number"); err != nil {
+		return nil, err
+	}
+	if err := a.validateLength(terminalLabel, a.TerminalLabelLength, "Terminal label"); err != nil {
+		return nil, err
 	}
-	terminalLabelValue, err := a.TerminalLabelValue(terminalLabel)
+	return []TLV{
+		{Tag: a.BillNumberTag, Length: len(billNumber), Value: billNumber},
+		{Tag: a.MobileNumberTag, Length: len(phoneNumber), Value: phoneNumber},
+		{Tag: a.StoreLabelTag, Length: len(storeLabel), Value: storeLabel},
+		{Tag: a.TerminalLabelTag, Length: len(terminalLabel), Value: terminalLabel},
+	}, nil
+}
+
+// Value combines all formatted values into a single string with a length prefix.
+func (a *AdditionalDataField) Value(storeLabel, phoneNumber, billNumber, terminalLabel string) (string, error) {
+	fields, err := a.Fields(storeLabel, phoneNumber, billNumber, terminalLabel)
 	if err != nil {
 		return "", err
 	}
 
-	combinedData := billNumberValue + phoneNumberValue + storeLabelValue + terminalLabelValue
+	combinedData := ""
+	for _, field := range fields {
+		combinedData += a.formatValue(field.Tag, field.Value)
+	}
 	lengthOfCombinedData := fmt.Sprintf("%02d", len(combinedData))
 
 	return a.AdditionalDataTag + lengthOfCombinedData + combinedData, nil
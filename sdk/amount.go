@@ -1,12 +1,157 @@
 package sdk
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
 
-// Amount holds the transaction amount tag and its maximum length.
+// Errors returned when an amount cannot be encoded.
+var (
+	ErrNegativeAmount  = errors.New("amount must not be negative")
+	ErrInvalidAmount   = errors.New("amount is not a finite decimal number")
+	ErrAmountPrecision = errors.New("amount has more decimal places than the currency allows")
+	ErrAmountTooLong   = errors.New("amount is too long")
+)
+
+// Money is an exact transaction amount counted in the minor units of its currency,
+// e.g. cents for USD and riel for KHR.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+// CurrencyPrecision returns the number of decimal places allowed for the currency.
+func CurrencyPrecision(currency string) (int, error) {
+	found, ok := LookupCurrency(currency)
+	if !ok {
+		return 0, fmt.Errorf("unknown currency code '%s'", currency)
+	}
+	return found.MinorUnits, nil
+}
+
+// NewMoney returns the amount of minor units of the currency.
+func NewMoney(minor int64, currency string) (Money, error) {
+	found, ok := LookupCurrency(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("unknown currency code '%s'", currency)
+	}
+	if minor < 0 {
+		return Money{}, ErrNegativeAmount
+	}
+	return Money{Minor: minor, Currency: found.Code}, nil
+}
+
+// ParseMoney parses a decimal amount such as "12.50" exactly.
+// Decimal places beyond the currency precision are rejected unless they are zeros.
+func ParseMoney(amount, currency string) (Money, error) {
+	found, ok := LookupCurrency(currency)
+	if !ok {
+		return Money{}, fmt.Errorf("unknown currency code '%s'", currency)
+	}
+	precision := found.MinorUnits
+	if strings.HasPrefix(amount, "-") {
+		return Money{}, ErrNegativeAmount
+	}
+	whole, fraction, _ := strings.Cut(amount, ".")
+	if whole == "" || !isDigits(whole) || !isDigits(fraction) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	whole = strings.TrimLeft(whole, "0")
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > precision {
+		return Money{}, fmt.Errorf("%w: %q allows %d", ErrAmountPrecision, amount, precision)
+	}
+	// 18 digits always fit in an int64
+	if len(whole)+precision > 18 {
+		return Money{}, fmt.Errorf("%w: %q", ErrAmountTooLong, amount)
+	}
+
+	digits := whole + fraction + strings.Repeat("0", precision-len(fraction))
+	minor, err := strconv.ParseInt("0"+digits, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	return Money{Minor: minor, Currency: found.Code}, nil
+}
+
+// MoneyFromFloat converts a float amount by rounding it to the minor units of the currency,
+// so a computed amount such as 0.1+0.2 (0.30000000000000004) becomes exactly 0.30 USD and 100.5 KHR becomes 101.
+// For exact amounts that must not be rounded, use ParseMoney or NewMoney.
+func MoneyFromFloat(amount float64, currency string) (Money, error) {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return Money{}, ErrInvalidAmount
+	}
+	if amount < 0 {
+		return Money{}, ErrNegativeAmount
+	}
+	precision, err := CurrencyPrecision(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	minor := math.Round(amount * math.Pow10(precision))
+	// 18 digits always fit in an int64, as in ParseMoney
+	if minor >= 1e18 {
+		return Money{}, fmt.Errorf("%w: %v", ErrAmountTooLong, amount)
+	}
+	return NewMoney(int64(minor), currency)
+}
+
+// String formats the amount as encoded in tag 54: unpadded, without trailing zeros.
+func (m Money) String() string {
+	return string(m.Append(nil))
+}
+
+// Append appends the amount as formatted by String to dst.
+func (m Money) Append(dst []byte) []byte {
+	precision, _ := CurrencyPrecision(m.Currency)
+	scale := int64(1)
+	for i := 0; i < precision; i++ {
+		scale *= 10
+	}
+	whole, fraction := m.Minor/scale, m.Minor%scale
+	dst = strconv.AppendInt(dst, whole, 10)
+	if fraction == 0 {
+		return dst
+	}
+
+	// Write the fraction digits zero-padded to the precision, then drop trailing zeros
+	dst = append(dst, '.')
+	for scale /= 10; scale > 0 && fraction > 0; scale /= 10 {
+		dst = append(dst, byte('0'+fraction/scale))
+		fraction %= scale
+	}
+	return dst
+}
+
+// Format returns the amount for display, with thousands separators and every decimal place of the currency, e.g. "1,234.50".
+func (m Money) Format() string {
+	precision, _ := CurrencyPrecision(m.Currency)
+	digits := fmt.Sprintf("%0*d", precision+1, m.Minor)
+	whole, fraction := digits[:len(digits)-precision], digits[len(digits)-precision:]
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	if fraction != "" {
+		return whole + "." + fraction
+	}
+	return whole
+}
+
+// isDigits reports whether s only contains ASCII digits.
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Amount holds the transaction amount tag and the maximum length of its value, 13 characters
+// as in the EMV specification, e.g. "1234567890.99" or "9999999999999".
 type Amount struct {
 	TransactionAmount string
 	MaxLength         int
@@ -20,43 +165,33 @@ func NewAmount(emv *EMV) *Amount {
 	}
 }
 
-// Value formats the amount according to the required structure.
-func (a *Amount) Value(amount interface{}) (string, error) {
-	// Ensure the amount is a valid type
-	var amountStr string
-	switch v := amount.(type) {
-	case int:
-		amountStr = fmt.Sprintf("%d", v)
-	case float64:
-		amountStr = fmt.Sprintf("%.2f", v)
-	case string:
-		_, err := strconv.ParseFloat(v, 64)
-		if err != nil {
-			return "", fmt.Errorf("invalid amount value: %s. Amount must be a number or a string representing a number", v)
-		}
-		amountStr = v
-	default:
-		return "", fmt.Errorf("amount must be a number or a string")
+// Validate checks that the amount is not negative and its formatted value fits in its data object.
+func (a *Amount) Validate(amount Money) error {
+	if amount.Minor < 0 {
+		return ErrNegativeAmount
 	}
-
-	// Remove trailing zeros after the decimal point
-	if strings.Contains(amountStr, ".") {
-		amountStr = strings.TrimRight(amountStr, "0")
-		amountStr = strings.TrimRight(amountStr, ".")
+	if _, err := CurrencyPrecision(amount.Currency); err != nil {
+		return err
 	}
 
 	// Ensure the length of the formatted amount does not exceed the max length
-	lengthOfAmount := len(amountStr) + 2 // Adding 2 for the transaction amount tag and length
-	if lengthOfAmount > a.MaxLength {
-		return "", fmt.Errorf("formatted amount exceeds maximum length of %d characters. Your input length: %d characters", a.MaxLength, lengthOfAmount)
+	var digits [32]byte
+	if length := len(amount.Append(digits[:0])); length > a.MaxLength {
+		return fmt.Errorf("%w: formatted amount exceeds maximum length of %d characters. Your input length: %d characters", ErrAmountTooLong, a.MaxLength, length)
 	}
+	return nil
+}
 
-	// Pad the amount to fit the required length
-	paddedAmountStr := fmt.Sprintf("%011s", amountStr) // Pad with leading zeros
+// Value formats the amount as the transaction amount tag.
+func (a *Amount) Value(amount Money) (string, error) {
+	if err := a.Validate(amount); err != nil {
+		return "", err
+	}
+	amountStr := amount.String()
 
 	// Calculate the length of the formatted amount string
-	lengthOfAmountStr := fmt.Sprintf("%02d", len(paddedAmountStr))
+	lengthOfAmountStr := fmt.Sprintf("%02d", len(amountStr))
 
 	// Return the formatted amount string with the tag, length, and amount
-	return fmt.Sprintf("%s%s%s", a.TransactionAmount, lengthOfAmountStr, paddedAmountStr), nil
+	return fmt.Sprintf("%s%s%s", a.TransactionAmount, lengthOfAmountStr, amountStr), nil
 }
@@ -0,0 +1,104 @@
+package sdk
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestAmountValue(t *testing.T) {
+	amount := NewAmount(NewEMV())
+	tests := []struct {
+		amount   float64
+		currency string
+		want     string
+	}{
+		{10000, "KHR", "540510000"},
+		{0, "KHR", "54010"},
+		{1, "USD", "54011"},
+		{1.5, "USD", "54031.5"},
+		{1.1, "USD", "54031.1"},
+		{0.01, "USD", "54040.01"},
+		{1234567890.99, "USD", "54131234567890.99"},
+		{9999999999999, "KHR", "54139999999999999"},
+		// Floats are rounded to the minor units of the currency
+		{100.5, "KHR", "5403101"},
+		{1.004, "USD", "54011"},
+		{0.125, "USD", "54040.13"},
+	}
+	for _, tc := range tests {
+		money, err := MoneyFromFloat(tc.amount, tc.currency)
+		if err != nil {
+			t.Errorf("MoneyFromFloat(%v, %s) error: %v", tc.amount, tc.currency, err)
+			continue
+		}
+		got, err := amount.Value(money)
+		if err != nil || got != tc.want {
+			t.Errorf("Value(%v %s) = %q, %v, want %q", tc.amount, tc.currency, got, err, tc.want)
+		}
+	}
+}
+
+func TestAmountRejectsInvalidValues(t *testing.T) {
+	amount := NewAmount(NewEMV())
+	tests := []struct {
+		amount   float64
+		currency string
+		want     error
+	}{
+		{-1, "USD", ErrNegativeAmount},
+		{math.NaN(), "USD", ErrInvalidAmount},
+		{math.Inf(1), "KHR", ErrInvalidAmount},
+		{1e13, "KHR", ErrAmountTooLong},
+		{12345678901.99, "USD", ErrAmountTooLong},
+		{1e300, "USD", ErrAmountTooLong},
+	}
+	for _, tc := range tests {
+		money, err := MoneyFromFloat(tc.amount, tc.currency)
+		if err == nil {
+			_, err = amount.Value(money)
+		}
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%v %s: error %v, want %v", tc.amount, tc.currency, err, tc.want)
+		}
+	}
+	if _, err := MoneyFromFloat(1, "XYZ"); err == nil {
+		t.Error("MoneyFromFloat accepted an unknown currency")
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount, currency string
+		minor            int64
+	}{
+		{"12.50", "USD", 1250},
+		{"12.500", "usd", 1250},
+		{"00000010000", "KHR", 10000},
+		{"7", "USD", 700},
+	}
+	for _, tc := range tests {
+		money, err := ParseMoney(tc.amount, tc.currency)
+		if err != nil || money.Minor != tc.minor {
+			t.Errorf("ParseMoney(%q, %s) = %+v, %v, want %d", tc.amount, tc.currency, money, err, tc.minor)
+		}
+	}
+	for _, amount := range []string{"", ".5", "1.2.3", "1e3", "-0.5", "12,000"} {
+		if _, err := ParseMoney(amount, "USD"); err == nil {
+			t.Errorf("ParseMoney(%q) accepted an invalid amount", amount)
+		}
+	}
+}
+
+func TestMoneyFromComputedFloat(t *testing.T) {
+	// Computed at run time, the sum is 0.30000000000000004 rather than 0.3
+	price, quantity := 0.1, 3.0
+	total := price * quantity
+	if total == 0.3 {
+		t.Fatalf("%v * %v is exactly 0.3, the test needs a float with a rounding error", price, quantity)
+	}
+	money, err := MoneyFromFloat(total, "USD")
+	if err != nil || money.Minor != 30 || money.String() != "0.3" {
+		t.Errorf("MoneyFromFloat(%v, USD) = %+v, %v, want 30 cents", total, money, err)
+	}
+}
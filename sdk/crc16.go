@@ -0,0 +1,103 @@
+package sdk
+
+import "hash"
+
+// crc16Polynomial is the CRC-CCITT polynomial used by KHQR.
+const crc16Polynomial = 0x1021
+
+// crc16Init is the initial CRC-16/CCITT-FALSE value.
+const crc16Init = 0xFFFF
+
+// crc16Table holds the CRC of every byte value, so the checksum is updated a byte at a time.
+var crc16Table = func() (table [256]uint16) {
+	for i := range table {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ crc16Polynomial
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// CRC16 computes a CRC-16/CCITT-FALSE checksum incrementally. It implements hash.Hash,
+// so the payload can be written to it as it streams, e.g. through an io.MultiWriter.
+type CRC16 struct {
+	crc uint16
+}
+
+var _ hash.Hash = (*CRC16)(nil)
+
+// NewCRC16 initializes and returns a CRC16 ready for writing.
+func NewCRC16() *CRC16 {
+	return &CRC16{crc: crc16Init}
+}
+
+// Write adds p to the checksum. It never returns an error.
+func (c *CRC16) Write(p []byte) (int, error) {
+	c.crc = UpdateCRC16(c.crc, p)
+	return len(p), nil
+}
+
+// WriteString adds s to the checksum without copying it. It never returns an error.
+func (c *CRC16) WriteString(s string) (int, error) {
+	c.crc = updateCRC16String(c.crc, s)
+	return len(s), nil
+}
+
+// Sum16 returns the checksum of the data written so far.
+func (c *CRC16) Sum16() uint16 {
+	return c.crc
+}
+
+// Sum appends the big-endian checksum to b.
+func (c *CRC16) Sum(b []byte) []byte {
+	return append(b, byte(c.crc>>8), byte(c.crc))
+}
+
+// Hex returns the checksum as four uppercase hex digits, as encoded in the CRC data object.
+func (c *CRC16) Hex() string {
+	return string(appendHex16(nil, c.crc))
+}
+
+// Reset clears the data written so far.
+func (c *CRC16) Reset() {
+	c.crc = crc16Init
+}
+
+// Size returns the checksum length in bytes.
+func (c *CRC16) Size() int {
+	return 2
+}
+
+// BlockSize returns 1, the CRC is updated a byte at a time.
+func (c *CRC16) BlockSize() int {
+	return 1
+}
+
+// UpdateCRC16 continues a CRC-16/CCITT-FALSE computation over data.
+// Start from 0xFFFF, the result of one call can be passed to the next to hash data in parts.
+func UpdateCRC16(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^b]
+	}
+	return crc
+}
+
+// updateCRC16String is UpdateCRC16 for a string.
+func updateCRC16String(crc uint16, data string) uint16 {
+	for i := 0; i < len(data); i++ {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^data[i]]
+	}
+	return crc
+}
+
+// appendHex16 appends a 16-bit value as four uppercase hex digits.
+func appendHex16(buf []byte, value uint16) []byte {
+	const hexDigits = "0123456789ABCDEF"
+	return append(buf, hexDigits[value>>12], hexDigits[value>>8&0xF], hexDigits[value>>4&0xF], hexDigits[value&0xF])
+}
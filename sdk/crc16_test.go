@@ -0,0 +1,83 @@
+package sdk
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// bitwiseCRC16 is the original bit-at-a-time implementation the table is checked against.
+func bitwiseCRC16(data string) int {
+	crc := 0xFFFF
+	for i := 0; i < len(data); i++ {
+		crc ^= int(data[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = (crc << 1) ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+			crc &= 0xFFFF
+		}
+	}
+	return crc
+}
+
+func TestCRC16MatchesBitwise(t *testing.T) {
+	c := NewCRC(NewEMV())
+	if got := c.CRC16Hex("123456789"); got != "29B1" {
+		t.Errorf("CRC16Hex(123456789) = %s, want the CRC-16/CCITT-FALSE check value 29B1", got)
+	}
+
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		data := make([]byte, random.Intn(300))
+		random.Read(data)
+		want := bitwiseCRC16(string(data))
+		if got := c.CalculateCRC16(string(data)); got != want {
+			t.Fatalf("CalculateCRC16(%x) = %04X, want %04X", data, got, want)
+		}
+		if got := c.CRC16Hex(string(data)); got != fmt.Sprintf("%04X", want) {
+			t.Fatalf("CRC16Hex(%x) = %s, want %04X", data, got, want)
+		}
+	}
+}
+
+func TestCRC16Incremental(t *testing.T) {
+	payload := dynamicQR[:len(dynamicQR)-4]
+	h := NewCRC16()
+	// Stream the payload in uneven chunks
+	for rest := payload; rest != ""; {
+		n := min(len(rest), 7)
+		io.WriteString(h, rest[:n])
+		rest = rest[n:]
+	}
+	if h.Hex() != dynamicQR[len(dynamicQR)-4:] {
+		t.Errorf("Hex() = %s, want %s", h.Hex(), dynamicQR[len(dynamicQR)-4:])
+	}
+	if sum := h.Sum([]byte("x")); len(sum) != 3 || uint16(sum[1])<<8|uint16(sum[2]) != h.Sum16() {
+		t.Errorf("Sum() = %x for %04X", sum, h.Sum16())
+	}
+
+	h.Reset()
+	h.Write([]byte("123456789"))
+	if h.Sum16() != 0x29B1 || h.Size() != 2 || h.BlockSize() != 1 {
+		t.Errorf("After Reset Sum16() = %04X", h.Sum16())
+	}
+}
+
+func BenchmarkCRC16Bitwise(b *testing.B) {
+	b.SetBytes(int64(len(dynamicQR)))
+	for i := 0; i < b.N; i++ {
+		bitwiseCRC16(dynamicQR)
+	}
+}
+
+func BenchmarkCRC16Table(b *testing.B) {
+	c := NewCRC(NewEMV())
+	b.SetBytes(int64(len(dynamicQR)))
+	for i := 0; i < b.N; i++ {
+		c.CalculateCRC16(dynamicQR)
+	}
+}
@@ -0,0 +1,71 @@
+package sdk
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed iso4217.csv
+var iso4217CSV string
+
+// Currency is an ISO 4217 currency.
+type Currency struct {
+	Code       string // alphabetic code, e.g. "USD"
+	Numeric    string // three digit numeric code as encoded in tag 53, e.g. "840"
+	MinorUnits int    // decimal places allowed in amounts
+	Name       string
+}
+
+// minorUnitOverrides replaces the ISO 4217 precision where Bakong differs.
+// KHR has 2 minor units in ISO 4217, but riel amounts are always whole in KHQR.
+var minorUnitOverrides = map[string]int{
+	"KHR": 0,
+}
+
+// Currency lookup tables, built from the embedded ISO 4217 table.
+var (
+	currenciesByCode    = make(map[string]Currency)
+	currenciesByNumeric = make(map[string]Currency)
+)
+
+func init() {
+	records, err := csv.NewReader(strings.NewReader(iso4217CSV)).ReadAll()
+	if err != nil {
+		panic("sdk: invalid iso4217.csv: " + err.Error())
+	}
+	for _, record := range records[1:] {
+		minorUnits, err := strconv.Atoi(record[2])
+		if err != nil {
+			panic("sdk: invalid minor units for " + record[0])
+		}
+		if override, ok := minorUnitOverrides[record[0]]; ok {
+			minorUnits = override
+		}
+		currency := Currency{Code: record[0], Numeric: record[1], MinorUnits: minorUnits, Name: record[3]}
+		currenciesByCode[currency.Code] = currency
+		currenciesByNumeric[currency.Numeric] = currency
+	}
+}
+
+// LookupCurrency returns the currency with the alphabetic or numeric code, e.g. "THB" or "764".
+func LookupCurrency(code string) (Currency, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if currency, ok := currenciesByCode[code]; ok {
+		return currency, true
+	}
+	currency, ok := currenciesByNumeric[code]
+	return currency, ok
+}
+
+// Currencies returns every known currency sorted by alphabetic code.
+func Currencies() []Currency {
+	currencies := make([]Currency, 0, len(currenciesByCode))
+	for _, currency := range currenciesByCode {
+		currencies = append(currencies, currency)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
+	return currencies
+}
@@ -0,0 +1,69 @@
+package sdk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookupCurrency(t *testing.T) {
+	tests := []struct {
+		code       string
+		want       string
+		numeric    string
+		minorUnits int
+	}{
+		{"THB", "THB", "764", 2},
+		{"764", "THB", "764", 2},
+		{"vnd", "VND", "704", 0},
+		{"KHR", "KHR", "116", 0}, // overridden, ISO 4217 lists 2
+		{"008", "ALL", "008", 2},
+		{"KWD", "KWD", "414", 3},
+	}
+	for _, tc := range tests {
+		currency, ok := LookupCurrency(tc.code)
+		if !ok || currency.Code != tc.want || currency.Numeric != tc.numeric || currency.MinorUnits != tc.minorUnits {
+			t.Errorf("LookupCurrency(%q) = %+v, %v", tc.code, currency, ok)
+		}
+	}
+	if _, ok := LookupCurrency("XYZ"); ok {
+		t.Error("LookupCurrency found XYZ")
+	}
+	if got := len(Currencies()); got < 150 {
+		t.Errorf("Currencies() returned %d currencies", got)
+	}
+}
+
+func TestTransactionCurrencyAllowlist(t *testing.T) {
+	tc := NewTransactionCurrency(NewEMV())
+	if got, err := tc.Value("usd"); err != nil || got != "5303840" {
+		t.Errorf("Value(usd) = %q, %v", got, err)
+	}
+	if _, err := tc.Value("THB"); err == nil {
+		t.Error("Value(THB) accepted a currency outside the allowlist")
+	}
+	tc.Allowed = append(tc.Allowed, "THB")
+	if got, err := tc.Value("764"); err != nil || got != "5303764" {
+		t.Errorf("Value(764) = %q, %v", got, err)
+	}
+}
+
+func TestMoneyWithThreeDecimals(t *testing.T) {
+	money, err := ParseMoney("1.005", "KWD")
+	if err != nil || money.Minor != 1005 || money.String() != "1.005" {
+		t.Errorf("ParseMoney(1.005 KWD) = %+v %q, %v", money, money.String(), err)
+	}
+}
+
+func TestDecodeForeignCurrency(t *testing.T) {
+	emv := NewEMV()
+	body := strings.Replace(dynamicQR[:len(dynamicQR)-8], "5303116", "5303764", 1)
+	qr := body + NewCRC(emv).Value(body)
+
+	decoded, err := NewDecoder(emv).Decode(qr)
+	if err != nil {
+		t.Fatalf("Failed to decode QR: %v", err)
+	}
+	if decoded.TransactionCurrency != "THB" || !decoded.CRCValid {
+		t.Errorf("Decoded currency %q, CRC valid %v", decoded.TransactionCurrency, decoded.CRCValid)
+	}
+}
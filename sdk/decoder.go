@@ -0,0 +1,151 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DecodedQR holds the fields read back from a KHQR payload.
+type DecodedQR struct {
+	PayloadFormatIndicator string `json:"payloadFormatIndicator"`
+	PointOfInitiation      string `json:"pointOfInitiation"`
+	Static                 bool   `json:"static"`
+	MerchantType           string `json:"merchantType,omitempty"`
+	BankAccount            string `json:"bankAccount,omitempty"`
+	BankCode               string `json:"bankCode,omitempty"` // account ID suffix, e.g. "wing"
+	BankName               string `json:"bankName,omitempty"` // empty when the suffix is not registered
+	MerchantID             string `json:"merchantId,omitempty"`
+	AcquiringBank          string `json:"acquiringBank,omitempty"`
+	MerchantCategoryCode   string `json:"merchantCategoryCode,omitempty"`
+	CountryCode            string `json:"countryCode,omitempty"`
+	MerchantName           string `json:"merchantName,omitempty"`
+	MerchantCity           string `json:"merchantCity,omitempty"`
+	Timestamp              string `json:"timestamp,omitempty"`
+	Amount                 string `json:"amount,omitempty"`
+	TransactionCurrency    string `json:"transactionCurrency,omitempty"`
+	BillNumber             string `json:"billNumber,omitempty"`
+	MobileNumber           string `json:"mobileNumber,omitempty"`
+	StoreLabel             string `json:"storeLabel,omitempty"`
+	TerminalLabel          string `json:"terminalLabel,omitempty"`
+	PurposeOfTransaction   string `json:"purposeOfTransaction,omitempty"`
+	LanguagePreference     string `json:"languagePreference,omitempty"`
+	MerchantNameAlternate  string `json:"merchantNameAlternate,omitempty"`
+	MerchantCityAlternate  string `json:"merchantCityAlternate,omitempty"`
+	CRC                    string `json:"crc"`
+	CRCValid               bool   `json:"crcValid"`
+	Fields                 []TLV  `json:"-"`
+}
+
+// Decoder holds the tag configuration used to read KHQR payloads.
+type Decoder struct {
+	emv *EMV
+	crc *CRC
+}
+
+// NewDecoder initializes and returns a new Decoder instance
+func NewDecoder(emv *EMV) *Decoder {
+	return &Decoder{
+		emv: emv,
+		crc: NewCRC(emv),
+	}
+}
+
+// Decode parses a KHQR payload into its fields and checks its CRC.
+func (d *Decoder) Decode(qr string) (*DecodedQR, error) {
+	if qr == "" {
+		return nil, errors.New("KHQR payload cannot be empty")
+	}
+
+	fields, err := ParseTLV(qr)
+	if err != nil {
+		return nil, err
+	}
+	if fields[0].Tag != d.emv.PayloadFormatIndicator {
+		return nil, fmt.Errorf("KHQR payload must start with tag %s, found tag %s", d.emv.PayloadFormatIndicator, fields[0].Tag)
+	}
+
+	decoded := &DecodedQR{Fields: fields}
+	for _, field := range fields {
+		switch field.Tag {
+		case d.emv.PayloadFormatIndicator:
+			decoded.PayloadFormatIndicator = field.Value
+		case d.emv.PointOfInitiationMethod:
+			decoded.PointOfInitiation = field.Value
+			decoded.Static = field.Value == d.emv.StaticQR
+		case d.emv.MerchantAccountInformationIndividual, d.emv.MerchantAccountInformationMerchant:
+			decoded.MerchantType = field.Tag
+			err = d.decodeTemplate(field, map[string]*string{
+				"00": &decoded.BankAccount,
+				"01": &decoded.MerchantID,
+				"02": &decoded.AcquiringBank,
+			})
+		case d.emv.MerchantCategoryCode:
+			decoded.MerchantCategoryCode = field.Value
+		case d.emv.CountryCode:
+			decoded.CountryCode = field.Value
+		case d.emv.MerchantName:
+			decoded.MerchantName = field.Value
+		case d.emv.MerchantCity:
+			decoded.MerchantCity = field.Value
+		case d.emv.TransactionAmount:
+			decoded.Amount = field.Value
+		case d.emv.TransactionCurrency:
+			decoded.TransactionCurrency = d.currencyCode(field.Value)
+		case d.emv.AdditionalDataTag:
+			err = d.decodeTemplate(field, map[string]*string{
+				d.emv.BillNumberTag:                 &decoded.BillNumber,
+				d.emv.AdditionDataFieldMobileNumber: &decoded.MobileNumber,
+				d.emv.StoreLabel:                    &decoded.StoreLabel,
+				d.emv.TerminalLabel:                 &decoded.TerminalLabel,
+				d.emv.PurposeOfTransaction:          &decoded.PurposeOfTransaction,
+			})
+		case d.emv.MerchantInformationLanguageTemplate:
+			err = d.decodeTemplate(field, map[string]*string{
+				d.emv.LanguagePreference:              &decoded.LanguagePreference,
+				d.emv.MerchantNameAlternativeLanguage: &decoded.MerchantNameAlternate,
+				d.emv.MerchantCityAlternativeLanguage: &decoded.MerchantCityAlternate,
+			})
+		case d.emv.TimestampTag:
+			err = d.decodeTemplate(field, map[string]*string{
+				d.emv.LanguagePreference: &decoded.Timestamp,
+			})
+		case d.emv.CRC:
+			decoded.CRC = field.Value
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if account, err := ParseAccountID(decoded.BankAccount); err == nil {
+		decoded.BankCode = account.Bank
+		if bank, ok := account.KnownBank(); ok {
+			decoded.BankName = bank.Name
+		}
+	}
+
+	decoded.CRCValid = d.crc.Verify(qr)
+	return decoded, nil
+}
+
+// decodeTemplate parses the sub-tags of a template field into the given destinations.
+func (d *Decoder) decodeTemplate(field TLV, destinations map[string]*string) error {
+	subFields, err := ParseTLV(field.Value)
+	if err != nil {
+		return fmt.Errorf("invalid template in tag %s: %w", field.Tag, err)
+	}
+	for _, subField := range subFields {
+		if destination, ok := destinations[subField.Tag]; ok {
+			*destination = subField.Value
+		}
+	}
+	return nil
+}
+
+// currencyCode maps a numeric currency code to its ISO 4217 alphabetic code when it is known.
+func (d *Decoder) currencyCode(numeric string) string {
+	if currency, ok := LookupCurrency(numeric); ok && currency.Numeric == numeric {
+		return currency.Code
+	}
+	return numeric
+}
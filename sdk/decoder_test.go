@@ -0,0 +1,77 @@
+package sdk
+
+import (
+	"strings"
+	"testing"
+)
+
+const dynamicQR = "00020101021229180014your_name@wing520459995802KH5909Your Name6010Phnom Penh991700131792415013025541100000010000530311662540112TRX0192837750211855123456780305MShop0710Cashier-016304423A"
+
+func TestDecode(t *testing.T) {
+	decoded, err := NewDecoder(NewEMV()).Decode(dynamicQR)
+	if err != nil {
+		t.Fatalf("Failed to decode QR: %v", err)
+	}
+
+	got := map[string]string{
+		"PointOfInitiation":   decoded.PointOfInitiation,
+		"BankAccount":         decoded.BankAccount,
+		"MerchantName":        decoded.MerchantName,
+		"MerchantCity":        decoded.MerchantCity,
+		"TransactionCurrency": decoded.TransactionCurrency,
+		"BillNumber":          decoded.BillNumber,
+		"MobileNumber":        decoded.MobileNumber,
+		"StoreLabel":          decoded.StoreLabel,
+		"TerminalLabel":       decoded.TerminalLabel,
+		"Timestamp":           decoded.Timestamp,
+	}
+	want := map[string]string{
+		"PointOfInitiation":   "12",
+		"BankAccount":         "your_name@wing",
+		"MerchantName":        "Your Name",
+		"MerchantCity":        "Phnom Penh",
+		"TransactionCurrency": "KHR",
+		"BillNumber":          "TRX019283775",
+		"MobileNumber":        "85512345678",
+		"StoreLabel":          "MShop",
+		"TerminalLabel":       "Cashier-01",
+		"Timestamp":           "1792415013025",
+	}
+	for field, value := range want {
+		if got[field] != value {
+			t.Errorf("%s = %q, want %q", field, got[field], value)
+		}
+	}
+	if decoded.Static {
+		t.Error("Static = true, want false")
+	}
+	if !decoded.CRCValid {
+		t.Error("CRCValid = false, want true")
+	}
+}
+
+func TestDecodeRejectsMalformedPayloads(t *testing.T) {
+	decoder := NewDecoder(NewEMV())
+	for _, qr := range []string{
+		"",
+		"000201010",
+		"000201010x12",
+		"5204599900020101",
+		dynamicQR[:len(dynamicQR)-2],
+	} {
+		if _, err := decoder.Decode(qr); err == nil {
+			t.Errorf("Decode(%q) succeeded, want error", qr)
+		}
+	}
+}
+
+func TestVerifyCRC(t *testing.T) {
+	crc := NewCRC(NewEMV())
+	if !crc.Verify(dynamicQR) {
+		t.Error("Verify rejected a valid payload")
+	}
+	tampered := strings.Replace(dynamicQR, "Your Name", "Your Nama", 1)
+	if crc.Verify(tampered) {
+		t.Error("Verify accepted a tampered payload")
+	}
+}
@@ -0,0 +1,198 @@
+package sdk
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DiffKind tells how a field differs between two payloads.
+type DiffKind string
+
+const (
+	DiffChanged DiffKind = "changed" // the field is in both payloads with different values
+	DiffRemoved DiffKind = "removed" // the field is only in the first payload
+	DiffAdded   DiffKind = "added"   // the field is only in the second payload
+)
+
+// FieldDiff is a difference between two payloads in a single field.
+type FieldDiff struct {
+	Path  string   `json:"path"` // tag, or template and sub-tag such as "62.01"
+	Name  string   `json:"name"` // e.g. "Bill number", empty for unknown tags
+	Kind  DiffKind `json:"kind"`
+	Left  string   `json:"left,omitempty"`
+	Right string   `json:"right,omitempty"`
+}
+
+// String returns the difference on one line, e.g. `62.01 Bill number: "INV-1" -> "INV-2"`.
+func (f FieldDiff) String() string {
+	label := f.Path
+	if f.Name != "" {
+		label += " " + f.Name
+	}
+	switch f.Kind {
+	case DiffRemoved:
+		return fmt.Sprintf("%s: removed %q", label, f.Left)
+	case DiffAdded:
+		return fmt.Sprintf("%s: added %q", label, f.Right)
+	}
+	return fmt.Sprintf("%s: %q -> %q", label, f.Left, f.Right)
+}
+
+// DiffOptions configures a comparison.
+type DiffOptions struct {
+	// IncludeVolatile also compares the timestamp and CRC, which differ between any two QRs created for the same order.
+	IncludeVolatile bool
+	// Ignore lists tags or template sub-tags left out of the comparison, e.g. "62.07" or the whole "64" template.
+	Ignore []string
+}
+
+// Comparer compares decoded payloads field by field.
+type Comparer struct {
+	emv       *EMV
+	templates map[string]bool
+	volatile  []string
+	names     map[string]string
+}
+
+// NewComparer initializes and returns a Comparer with EMV configurations.
+func NewComparer(emv *EMV) *Comparer {
+	return &Comparer{
+		emv:       emv,
+		templates: templateTags(emv),
+		volatile:  []string{emv.TimestampTag, emv.CRC},
+		names:     fieldNames(emv),
+	}
+}
+
+// fieldNames returns the name of every known tag and template sub-tag by path.
+func fieldNames(emv *EMV) map[string]string {
+	names := map[string]string{
+		emv.PayloadFormatIndicator:  "Payload format",
+		emv.PointOfInitiationMethod: "Point of initiation",
+		emv.MerchantCategoryCode:    "Category code",
+		emv.TransactionCurrency:     "Currency",
+		emv.TransactionAmount:       "Amount",
+		emv.CountryCode:             "Country code",
+		emv.MerchantName:            "Merchant name",
+		emv.MerchantCity:            "Merchant city",
+		emv.CRC:                     "CRC",
+
+		emv.AdditionalDataTag:                                           "Additional data",
+		emv.AdditionalDataTag + "." + emv.BillNumberTag:                 "Bill number",
+		emv.AdditionalDataTag + "." + emv.AdditionDataFieldMobileNumber: "Mobile number",
+		emv.AdditionalDataTag + "." + emv.StoreLabel:                    "Store label",
+		emv.AdditionalDataTag + "." + emv.TerminalLabel:                 "Terminal label",
+		emv.AdditionalDataTag + "." + emv.PurposeOfTransaction:          "Purpose",
+
+		emv.MerchantInformationLanguageTemplate:                                             "Merchant information language",
+		emv.MerchantInformationLanguageTemplate + "." + emv.LanguagePreference:              "Language preference",
+		emv.MerchantInformationLanguageTemplate + "." + emv.MerchantNameAlternativeLanguage: "Name (alternate)",
+		emv.MerchantInformationLanguageTemplate + "." + emv.MerchantCityAlternativeLanguage: "City (alternate)",
+
+		emv.TimestampTag: "Timestamp template",
+		emv.TimestampTag + "." + emv.LanguagePreference: "Timestamp",
+	}
+	for _, tag := range []string{emv.MerchantAccountInformationIndividual, emv.MerchantAccountInformationMerchant} {
+		names[tag] = "Merchant account"
+		names[tag+".00"] = "Bank account"
+		names[tag+".01"] = "Merchant ID"
+		names[tag+".02"] = "Acquiring bank"
+	}
+	return names
+}
+
+// Equal reports whether the payloads have no differences with the default options.
+func (c *Comparer) Equal(a, b *DecodedQR) bool {
+	return len(c.Diff(a, b, DiffOptions{})) == 0
+}
+
+// Diff lists the fields that differ between the payloads, ordered by path.
+// Templates are compared sub-tag by sub-tag, the order of fields does not matter
+// and amounts are compared by value so "1.5" equals "1.50".
+func (c *Comparer) Diff(a, b *DecodedQR, opts DiffOptions) []FieldDiff {
+	ignore := opts.Ignore
+	if !opts.IncludeVolatile {
+		ignore = append(ignore[:len(ignore):len(ignore)], c.volatile...)
+	}
+	left, right := c.flatten(a.Fields), c.flatten(b.Fields)
+
+	paths := make([]string, 0, len(left)+len(right))
+	for path := range left {
+		paths = append(paths, path)
+	}
+	for path := range right {
+		if _, ok := left[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var diffs []FieldDiff
+	for _, path := range paths {
+		if ignored(path, ignore) {
+			continue
+		}
+		l, inLeft := left[path]
+		r, inRight := right[path]
+		diff := FieldDiff{Path: path, Name: c.names[path], Left: l, Right: r}
+		switch {
+		case !inRight:
+			diff.Kind = DiffRemoved
+		case !inLeft:
+			diff.Kind = DiffAdded
+		case l == r || path == c.emv.TransactionAmount && sameAmount(l, a.TransactionCurrency, r, b.TransactionCurrency):
+			continue
+		default:
+			diff.Kind = DiffChanged
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// flatten maps the path of every field to its value, descending into templates.
+// A template that cannot be parsed is kept whole and empty fields count as absent.
+func (c *Comparer) flatten(fields []TLV) map[string]string {
+	values := make(map[string]string, len(fields))
+	for _, field := range fields {
+		if _, seen := values[field.Tag]; seen {
+			continue
+		}
+		if c.templates[field.Tag] {
+			if subFields, err := ParseTLV(field.Value); err == nil {
+				for _, subField := range subFields {
+					path := field.Tag + "." + subField.Tag
+					if _, seen := values[path]; !seen && subField.Value != "" {
+						values[path] = subField.Value
+					}
+				}
+				continue
+			}
+		}
+		if field.Value != "" {
+			values[field.Tag] = field.Value
+		}
+	}
+	return values
+}
+
+// ignored reports whether the path or its template is in the list.
+func ignored(path string, ignore []string) bool {
+	for _, prefix := range ignore {
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// sameAmount reports whether both amounts have the same value in the same currency.
+func sameAmount(a, aCurrency, b, bCurrency string) bool {
+	left, err := ParseMoney(a, aCurrency)
+	if err != nil {
+		return false
+	}
+	right, err := ParseMoney(b, bCurrency)
+	return err == nil && left == right
+}
@@ -0,0 +1,50 @@
+package sdk
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	emv := NewEMV()
+	decoder, comparer, crc := NewDecoder(emv), NewComparer(emv), NewCRC(emv)
+	tlv := func(tag, value string) string { return fmt.Sprintf("%s%02d%s", tag, len(value), value) }
+	decode := func(fields ...string) *DecodedQR {
+		t.Helper()
+		qr := "000201" + "010212" + tlv("29", tlv("00", "your_name@wing")) + "5204599958" + "02KH" + tlv("59", "Your Name")
+		for _, field := range fields {
+			qr += field
+		}
+		decoded, err := decoder.Decode(qr + crc.Value(qr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return decoded
+	}
+
+	// The same order created at two different times, the second with trailing zeros in the amount
+	a := decode(tlv("99", tlv("00", "1700000000000")), tlv("54", "10000"), "5303116", tlv("62", tlv("01", "TRX019283775")))
+	b := decode(tlv("99", tlv("00", "1700000099999")), tlv("54", "10000.00"), "5303116", tlv("62", tlv("01", "TRX019283775")))
+	if !comparer.Equal(a, b) {
+		t.Errorf("expected payloads differing only in timestamp, CRC and amount format to be equal: %v", comparer.Diff(a, b, DiffOptions{}))
+	}
+
+	c := decode(tlv("99", tlv("00", "1700000000000")), tlv("54", "1000"), "5303116", tlv("62", tlv("01", "TRX019283775")+tlv("03", "MShop")))
+	want := []FieldDiff{
+		{Path: "54", Name: "Amount", Kind: DiffChanged, Left: "10000", Right: "1000"},
+		{Path: "62.03", Name: "Store label", Kind: DiffAdded, Right: "MShop"},
+	}
+	if got := comparer.Diff(a, c, DiffOptions{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+	if got := comparer.Diff(c, a, DiffOptions{Ignore: []string{"54"}}); len(got) != 1 || got[0].Kind != DiffRemoved {
+		t.Errorf("Diff() ignoring the amount = %v", got)
+	}
+	if got := comparer.Diff(a, b, DiffOptions{IncludeVolatile: true}); len(got) != 2 || got[0].Path != "63" || got[1].Path != "99.00" {
+		t.Errorf("Diff() with volatile tags = %v", got)
+	}
+	if s := want[1].String(); s != `62.03 Store label: added "MShop"` {
+		t.Errorf("String() = %s", s)
+	}
+}
@@ -0,0 +1,159 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrFieldTooLong is returned for a data object value longer than 99 bytes.
+var ErrFieldTooLong = errors.New("data object value cannot exceed 99 bytes")
+
+// Encoder writes data objects straight to an io.Writer while accumulating the CRC,
+// so a payload can be streamed to a file, an HTTP response or a renderer without building it in memory.
+// The first error, from the writer or an invalid data object, is kept and returned by every later call,
+// so a payload missing a field never gets a valid CRC.
+type Encoder struct {
+	w       io.Writer
+	crc     CRC16
+	crcTag  string
+	written int64
+	err     error
+	header  [4]byte
+}
+
+// NewEncoder initializes and returns an Encoder writing to w.
+func NewEncoder(w io.Writer, emv *EMV) *Encoder {
+	return &Encoder{w: w, crc: CRC16{crc: crc16Init}, crcTag: emv.DefaultCRCTag}
+}
+
+// WriteField writes a data object with the tag and value.
+func (e *Encoder) WriteField(tag, value string) error {
+	if err := e.writeHeader(tag, len(value)); err != nil {
+		return err
+	}
+	return e.WriteRaw(value)
+}
+
+// WriteFieldBytes is WriteField for a value held in a byte slice.
+func (e *Encoder) WriteFieldBytes(tag string, value []byte) error {
+	if err := e.writeHeader(tag, len(value)); err != nil {
+		return err
+	}
+	return e.write(value)
+}
+
+// WriteTemplate writes a data object whose value is the sub-fields, e.g. the additional data field template.
+func (e *Encoder) WriteTemplate(tag string, fields []TLV) error {
+	length := 0
+	for _, field := range fields {
+		length += 4 + len(field.Value)
+	}
+	if err := e.writeHeader(tag, length); err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if err := e.WriteField(field.Tag, field.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRaw writes data objects that are already encoded, e.g. the point of initiation.
+func (e *Encoder) WriteRaw(data string) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.crc.WriteString(data)
+	n, err := io.WriteString(e.w, data)
+	e.written += int64(n)
+	e.err = err
+	return err
+}
+
+// WriteRawBytes is WriteRaw for data objects held in a byte slice, e.g. those built with AppendField.
+func (e *Encoder) WriteRawBytes(data []byte) error {
+	return e.write(data)
+}
+
+// WriteCRC ends the payload with the CRC data object covering everything written so far.
+func (e *Encoder) WriteCRC() error {
+	if err := e.WriteRaw(e.crcTag); err != nil {
+		return err
+	}
+	var digits [4]byte
+	return e.write(appendHex16(digits[:0], e.crc.Sum16()))
+}
+
+// Written returns the number of bytes written so far.
+func (e *Encoder) Written() int64 {
+	return e.written
+}
+
+// Err returns the first error of the writer or of an invalid data object, if any.
+func (e *Encoder) Err() error {
+	return e.err
+}
+
+// writeHeader writes the two digit tag and the value length, keeping the error of an invalid one.
+func (e *Encoder) writeHeader(tag string, length int) error {
+	if e.err != nil {
+		return e.err
+	}
+	if len(tag) != 2 {
+		e.err = fmt.Errorf("invalid tag %q, tags are two digits", tag)
+		return e.err
+	}
+	if length > 99 {
+		e.err = fmt.Errorf("%w: tag %s has %d bytes", ErrFieldTooLong, tag, length)
+		return e.err
+	}
+	return e.write(AppendHeader(e.header[:0], tag, length))
+}
+
+// write writes p to the writer and the CRC.
+func (e *Encoder) write(p []byte) error {
+	if e.err != nil {
+		return e.err
+	}
+	e.crc.Write(p)
+	n, err := e.w.Write(p)
+	e.written += int64(n)
+	e.err = err
+	return err
+}
+
+// AppendField appends a data object with the tag and value to buf, for payloads built in memory without an Encoder.
+// Unlike the Encoder it does not check the tag and length, so the value must already be validated.
+func AppendField(buf []byte, tag, value string) []byte {
+	return append(AppendHeader(buf, tag, len(value)), value...)
+}
+
+// AppendFieldBytes is AppendField for a value held in a byte slice.
+func AppendFieldBytes(buf []byte, tag string, value []byte) []byte {
+	return append(AppendHeader(buf, tag, len(value)), value...)
+}
+
+// AppendTemplate appends a data object whose value is the sub-fields.
+func AppendTemplate(buf []byte, tag string, fields []TLV) []byte {
+	length := 0
+	for _, field := range fields {
+		length += 4 + len(field.Value)
+	}
+	buf = AppendHeader(buf, tag, length)
+	for _, field := range fields {
+		buf = AppendField(buf, field.Tag, field.Value)
+	}
+	return buf
+}
+
+// AppendCRC appends the CRC data object with the tag. crc is the UpdateCRC16 state over the payload before it.
+func AppendCRC(buf []byte, crcTag string, crc uint16) []byte {
+	return appendHex16(append(buf, crcTag...), updateCRC16String(crc, crcTag))
+}
+
+// appendHeader appends the two digit tag and the value length.
+func AppendHeader(buf []byte, tag string, length int) []byte {
+	return append(buf, tag[0], tag[1], byte('0'+length/10), byte('0'+length%10))
+}
@@ -0,0 +1,101 @@
+package sdk
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// failingWriter accepts limit bytes and then fails.
+type failingWriter struct {
+	limit int
+}
+
+var errWriterFull = errors.New("writer full")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, errWriterFull
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestEncoder(t *testing.T) {
+	emv := NewEMV()
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, emv)
+	e.WriteField("00", "01")
+	e.WriteRaw("010212")
+	e.WriteTemplate("29", []TLV{{Tag: "00", Value: "your_name@wing"}})
+	e.WriteFieldBytes("54", []byte("10000"))
+	if err := e.WriteCRC(); err != nil {
+		t.Fatalf("WriteCRC() error: %v", err)
+	}
+
+	body := "000201010212291800" + "14your_name@wing" + "540510000"
+	want := body + NewCRC(emv).Value(body)
+	if buf.String() != want || e.Written() != int64(len(want)) {
+		t.Errorf("Encoded %q (%d bytes), want %q", buf.String(), e.Written(), want)
+	}
+	if !NewCRC(emv).Verify(buf.String()) {
+		t.Error("Encoded payload has an invalid CRC")
+	}
+}
+
+func TestEncoderErrors(t *testing.T) {
+	e := NewEncoder(&bytes.Buffer{}, NewEMV())
+	if err := e.WriteField("5", "x"); err == nil {
+		t.Error("WriteField accepted a one digit tag")
+	}
+	if err := e.WriteCRC(); err == nil || e.Err() == nil {
+		t.Error("WriteCRC after an invalid tag succeeded")
+	}
+
+	var buf bytes.Buffer
+	e = NewEncoder(&buf, NewEMV())
+	e.WriteField("00", "01")
+	long := string(make([]byte, 100))
+	if err := e.WriteField("62", long); !errors.Is(err, ErrFieldTooLong) {
+		t.Errorf("WriteField(100 bytes) error = %v, want ErrFieldTooLong", err)
+	}
+	// The field is not written, so the payload must not be ended with a valid CRC
+	if err := e.WriteCRC(); !errors.Is(err, ErrFieldTooLong) || !errors.Is(e.Err(), ErrFieldTooLong) {
+		t.Errorf("WriteCRC after a too long field error = %v, want ErrFieldTooLong", err)
+	}
+	if buf.String() != "000201" {
+		t.Errorf("Encoded %q after a too long field, want only the fields before it", buf.String())
+	}
+
+	e = NewEncoder(&failingWriter{limit: 6}, NewEMV())
+	e.WriteField("00", "01")
+	if err := e.WriteField("01", "12"); !errors.Is(err, errWriterFull) {
+		t.Errorf("WriteField error = %v, want the writer error", err)
+	}
+	if err := e.WriteCRC(); !errors.Is(err, errWriterFull) || !errors.Is(e.Err(), errWriterFull) {
+		t.Errorf("WriteCRC after a failed write error = %v", err)
+	}
+	if e.Written() != 6 {
+		t.Errorf("Written() = %d, want 6", e.Written())
+	}
+}
+
+func TestAppendMatchesEncoder(t *testing.T) {
+	emv := NewEMV()
+	var buf bytes.Buffer
+	e := NewEncoder(&buf, emv)
+	e.WriteField("00", "01")
+	e.WriteTemplate("29", []TLV{{Tag: "00", Value: "your_name@wing"}})
+	e.WriteFieldBytes("54", []byte("10000"))
+	e.WriteCRC()
+
+	payload := AppendField(nil, "00", "01")
+	payload = AppendTemplate(payload, "29", []TLV{{Tag: "00", Value: "your_name@wing"}})
+	payload = AppendFieldBytes(payload, "54", []byte("10000"))
+	payload = AppendCRC(payload, emv.DefaultCRCTag, UpdateCRC16(0xFFFF, payload))
+	if string(payload) != buf.String() {
+		t.Errorf("Appended %q, encoded %q", payload, buf.String())
+	}
+}
@@ -0,0 +1,316 @@
+package sdk
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Node is a data object of an explained payload.
+type Node struct {
+	Tag      string   `json:"tag"`
+	Name     string   `json:"name,omitempty"` // empty for tags the EMV configuration does not name
+	Length   int      `json:"length"`
+	Value    string   `json:"value"`
+	Note     string   `json:"note,omitempty"` // meaning of the value, e.g. "dynamic" or "KHR"
+	Children []*Node  `json:"children,omitempty"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Explanation is a payload broken down into its data objects, with every spec violation found.
+// Unlike Decode, explaining never fails: a payload that cannot be parsed to the end keeps the nodes read so far.
+type Explanation struct {
+	Nodes    []*Node  `json:"nodes"`
+	CRCValid bool     `json:"crcValid"`
+	Problems []string `json:"problems,omitempty"` // problems of the payload as a whole, e.g. a missing tag
+}
+
+// Valid reports whether the CRC matches and no problem was found.
+func (e *Explanation) Valid() bool {
+	if !e.CRCValid || len(e.Problems) > 0 {
+		return false
+	}
+	var walk func(nodes []*Node) bool
+	walk = func(nodes []*Node) bool {
+		for _, node := range nodes {
+			if len(node.Problems) > 0 || !walk(node.Children) {
+				return false
+			}
+		}
+		return true
+	}
+	return walk(e.Nodes)
+}
+
+// String renders the explanation as an indented tree, one node per line with its
+// tag, name, length and value, followed by the problems of the node marked with "!".
+func (e *Explanation) String() string {
+	// Align the lengths after the longest tag and name
+	width := 0
+	var measure func(nodes []*Node, indent int)
+	measure = func(nodes []*Node, indent int) {
+		for _, node := range nodes {
+			width = max(width, indent+len(node.Tag)+1+len(node.Name))
+			measure(node.Children, indent+2)
+		}
+	}
+	measure(e.Nodes, 0)
+
+	var b strings.Builder
+	var render func(nodes []*Node, indent int)
+	render = func(nodes []*Node, indent int) {
+		for _, node := range nodes {
+			label := strings.Repeat(" ", indent) + node.Tag + " " + node.Name
+			fmt.Fprintf(&b, "%-*s  %02d", width, label, node.Length)
+			if len(node.Children) == 0 && node.Value != "" {
+				fmt.Fprintf(&b, "  %s", node.Value)
+			}
+			if node.Note != "" {
+				fmt.Fprintf(&b, "  (%s)", node.Note)
+			}
+			b.WriteByte('\n')
+			for _, problem := range node.Problems {
+				fmt.Fprintf(&b, "%s  ! %s\n", strings.Repeat(" ", indent), problem)
+			}
+			render(node.Children, indent+2)
+		}
+	}
+	render(e.Nodes, 0)
+	for _, problem := range e.Problems {
+		fmt.Fprintf(&b, "! %s\n", problem)
+	}
+	return b.String()
+}
+
+// Explainer breaks payloads down into annotated data objects.
+type Explainer struct {
+	emv                    *EMV
+	crc                    *CRC
+	names                  map[string]string
+	templates              map[string]bool
+	merchantName           *MerchantName
+	merchantCity           *MerchantCity
+	transactionCurrency    *TransactionCurrency
+	globalUniqueIdentifier *GlobalUniqueIdentifier
+}
+
+// NewExplainer initializes and returns an Explainer with EMV configurations.
+func NewExplainer(emv *EMV) *Explainer {
+	return &Explainer{
+		emv:                    emv,
+		crc:                    NewCRC(emv),
+		names:                  fieldNames(emv),
+		templates:              templateTags(emv),
+		merchantName:           NewMerchantName(emv),
+		merchantCity:           NewMerchantCity(emv),
+		transactionCurrency:    NewTransactionCurrency(emv),
+		globalUniqueIdentifier: NewGlobalUniqueIdentifier(emv),
+	}
+}
+
+// Explain breaks the payload down into its data objects, expanding templates and checking every field.
+func (x *Explainer) Explain(qr string) *Explanation {
+	explanation := &Explanation{CRCValid: x.crc.Verify(qr)}
+	nodes, err := parseNodes(qr)
+	if err != nil {
+		explanation.Problems = append(explanation.Problems, err.Error())
+	}
+	explanation.Nodes = nodes
+
+	seen := make(map[string]bool, len(nodes))
+	for i, node := range nodes {
+		node.Name = x.names[node.Tag]
+		if seen[node.Tag] {
+			node.Problems = append(node.Problems, "duplicate tag")
+		}
+		seen[node.Tag] = true
+		if x.templates[node.Tag] {
+			children, err := parseNodes(node.Value)
+			if err != nil {
+				node.Problems = append(node.Problems, "invalid template: "+err.Error())
+			}
+			node.Children = children
+			for _, child := range children {
+				child.Name = x.names[node.Tag+"."+child.Tag]
+			}
+		}
+		x.check(node, i, len(nodes), qr, nodes)
+	}
+
+	// Mandatory data objects
+	for _, tag := range []string{x.emv.PayloadFormatIndicator, x.emv.PointOfInitiationMethod, x.emv.MerchantCategoryCode, x.emv.TransactionCurrency, x.emv.CountryCode, x.emv.MerchantName, x.emv.MerchantCity, x.emv.CRC} {
+		if !seen[tag] {
+			explanation.Problems = append(explanation.Problems, fmt.Sprintf("missing tag %s %s", tag, x.names[tag]))
+		}
+	}
+	if !seen[x.emv.MerchantAccountInformationIndividual] && !seen[x.emv.MerchantAccountInformationMerchant] {
+		explanation.Problems = append(explanation.Problems, fmt.Sprintf("missing merchant account, tag %s or %s", x.emv.MerchantAccountInformationIndividual, x.emv.MerchantAccountInformationMerchant))
+	}
+	return explanation
+}
+
+// check annotates the node at index i of the payload with its meaning and problems.
+func (x *Explainer) check(node *Node, i, count int, qr string, nodes []*Node) {
+	emv := x.emv
+	problem := func(format string, args ...interface{}) {
+		node.Problems = append(node.Problems, fmt.Sprintf(format, args...))
+	}
+	switch node.Tag {
+	case emv.PayloadFormatIndicator:
+		if i != 0 {
+			problem("payload format indicator must be the first tag")
+		}
+		if node.Value != emv.DefaultPayloadFormatIndicator {
+			problem("payload format indicator must be %q", emv.DefaultPayloadFormatIndicator)
+		}
+	case emv.PointOfInitiationMethod:
+		amount := findNode(nodes, emv.TransactionAmount)
+		switch node.Value {
+		case emv.StaticQR:
+			node.Note = "static"
+			if amount != nil {
+				problem("a static QR must not have an amount")
+			}
+		case emv.DynamicQR:
+			node.Note = "dynamic"
+			if amount == nil {
+				problem("a dynamic QR must have an amount, tag %s", emv.TransactionAmount)
+			}
+		default:
+			problem("point of initiation must be %q for static or %q for dynamic QRs", emv.StaticQR, emv.DynamicQR)
+		}
+	case emv.MerchantAccountInformationIndividual, emv.MerchantAccountInformationMerchant:
+		account := findNode(node.Children, "00")
+		if account == nil {
+			problem("missing sub-tag 00 Bank account")
+			break
+		}
+		if err := x.globalUniqueIdentifier.Validate(account.Value); err != nil {
+			account.Problems = append(account.Problems, err.Error())
+			break
+		}
+		id, _ := ParseAccountID(account.Value)
+		if bank, ok := id.KnownBank(); ok {
+			account.Note = bank.Name
+		} else {
+			account.Note = "unknown bank " + id.Bank
+		}
+	case emv.MerchantCategoryCode:
+		if len(node.Value) != 4 || !isDigits(node.Value) {
+			problem("category code must be 4 digits")
+		}
+	case emv.TransactionCurrency:
+		currency, err := x.transactionCurrency.Resolve(node.Value)
+		if err != nil {
+			problem("%s", err)
+		} else if currency.Numeric != node.Value {
+			problem("currency must be the numeric code %s", currency.Numeric)
+		} else {
+			node.Note = currency.Code
+		}
+	case emv.TransactionAmount:
+		currency := ""
+		if currencyNode := findNode(nodes, emv.TransactionCurrency); currencyNode != nil {
+			if found, ok := LookupCurrency(currencyNode.Value); ok {
+				currency = found.Code
+			}
+		}
+		if currency == "" {
+			// Without a known currency only the format can be checked
+			currency = "USD"
+		}
+		if _, err := ParseMoney(node.Value, currency); err != nil {
+			problem("%s", err)
+		}
+	case emv.CountryCode:
+		if len(node.Value) != 2 || strings.Trim(node.Value, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			problem("country code must be 2 upper case letters")
+		}
+	case emv.MerchantName:
+		if err := x.merchantName.Validate(node.Value); err != nil {
+			problem("%s", err)
+		}
+	case emv.MerchantCity:
+		if err := x.merchantCity.Validate(node.Value); err != nil {
+			problem("%s", err)
+		}
+	case emv.AdditionalDataTag:
+		limits := map[string]int{
+			emv.BillNumberTag:                 emv.InvalidLengthBillNumber,
+			emv.AdditionDataFieldMobileNumber: emv.InvalidLengthMobileNumber,
+			emv.StoreLabel:                    emv.InvalidLengthStoreLabel,
+			emv.TerminalLabel:                 emv.InvalidLengthTerminalLabel,
+		}
+		for _, child := range node.Children {
+			if limit, ok := limits[child.Tag]; ok && child.Length > limit {
+				child.Problems = append(child.Problems, fmt.Sprintf("%s cannot exceed %d characters", strings.ToLower(child.Name), limit))
+			}
+			if child.Tag == emv.AdditionDataFieldMobileNumber && child.Value != "" {
+				if _, err := ParsePhoneNumber(child.Value); err != nil {
+					child.Problems = append(child.Problems, err.Error())
+				}
+			}
+		}
+	case emv.TimestampTag:
+		if timestamp := findNode(node.Children, emv.LanguagePreference); timestamp != nil {
+			ms, err := strconv.ParseInt(timestamp.Value, 10, 64)
+			if err != nil || len(timestamp.Value) != emv.InvalidLengthTimestamp {
+				timestamp.Problems = append(timestamp.Problems, fmt.Sprintf("timestamp must be %d digits of milliseconds since 1970", emv.InvalidLengthTimestamp))
+			} else {
+				timestamp.Note = time.UnixMilli(ms).UTC().Format(time.RFC3339)
+			}
+		}
+	case emv.CRC:
+		if i != count-1 {
+			problem("CRC must be the last tag")
+		}
+		if node.Length != 4 {
+			problem("CRC must be 4 hex digits")
+			break
+		}
+		// The CRC covers everything before its value, including its own tag and length
+		end := strings.LastIndex(qr, emv.DefaultCRCTag+node.Value)
+		if end < 0 {
+			break
+		}
+		expected := x.crc.CRC16Hex(qr[:end+len(emv.DefaultCRCTag)])
+		if strings.EqualFold(expected, node.Value) {
+			node.Note = "valid"
+		} else {
+			node.Note = "INVALID"
+			problem("CRC does not match the payload, expected %s", expected)
+		}
+	}
+}
+
+// templateTags returns the tags whose value is itself made of data objects.
+func templateTags(emv *EMV) map[string]bool {
+	return map[string]bool{
+		emv.MerchantAccountInformationIndividual: true,
+		emv.MerchantAccountInformationMerchant:   true,
+		emv.AdditionalDataTag:                    true,
+		emv.MerchantInformationLanguageTemplate:  true,
+		emv.TimestampTag:                         true,
+	}
+}
+
+// parseNodes splits data into nodes, returning the nodes read before an error.
+func parseNodes(data string) ([]*Node, error) {
+	fields, err := ParseTLV(data)
+	nodes := make([]*Node, len(fields))
+	for i, field := range fields {
+		nodes[i] = &Node{Tag: field.Tag, Length: field.Length, Value: field.Value}
+	}
+	return nodes, err
+}
+
+// findNode returns the first node with the tag, or nil.
+func findNode(nodes []*Node, tag string) *Node {
+	for _, node := range nodes {
+		if node.Tag == tag {
+			return node
+		}
+	}
+	return nil
+}
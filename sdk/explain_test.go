@@ -0,0 +1,68 @@
+package sdk
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	explainer := NewExplainer(NewEMV())
+
+	const qr = "00020101021229180014your_name@wing520459995802KH5909Your Name6010Phnom Penh991700131700000000000540510000530311662160112TRX0192837756304E6A0"
+	explanation := explainer.Explain(qr)
+	want := strings.Join([]string{
+		"00 Payload format       02  01",
+		"01 Point of initiation  02  12  (dynamic)",
+		"29 Merchant account     18",
+		"  00 Bank account       14  your_name@wing  (Wing Bank)",
+		"52 Category code        04  5999",
+		"58 Country code         02  KH",
+		"59 Merchant name        09  Your Name",
+		"60 Merchant city        10  Phnom Penh",
+		"99 Timestamp template   17",
+		"  00 Timestamp          13  1700000000000  (2023-11-14T22:13:20Z)",
+		"54 Amount               05  10000",
+		"53 Currency             03  116  (KHR)",
+		"62 Additional data      16",
+		"  01 Bill number        12  TRX019283775",
+		"63 CRC                  04  E6A0  (valid)",
+		"",
+	}, "\n")
+	if got := explanation.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+	if !explanation.Valid() {
+		t.Errorf("expected a valid explanation: %+v", explanation.Problems)
+	}
+
+	// Every problem is reported, not just the first
+	explanation = explainer.Explain("00020101021129180014your_name@wing52045999580299" + "5906Shop  6010Phnom Penh54011530399962060105AB63040000")
+	var problems []string
+	var collect func(nodes []*Node)
+	collect = func(nodes []*Node) {
+		for _, node := range nodes {
+			problems = append(problems, node.Problems...)
+			collect(node.Children)
+		}
+	}
+	collect(explanation.Nodes)
+	problems = append(problems, explanation.Problems...)
+	for _, want := range []string{
+		"a static QR must not have an amount",
+		"country code must be 2 upper case letters",
+		"invalid currency code '999', it is not an ISO 4217 currency",
+		"invalid template: tag 01 declares length 5 but only 2 characters remain",
+		"CRC does not match the payload, expected",
+	} {
+		found := false
+		for _, problem := range problems {
+			found = found || strings.HasPrefix(problem, want)
+		}
+		if !found {
+			t.Errorf("expected the problem %q in %q", want, problems)
+		}
+	}
+	if explanation.Valid() {
+		t.Error("expected an invalid explanation")
+	}
+}
@@ -20,14 +20,27 @@ func NewGlobalUniqueIdentifier(emv *EMV) *GlobalUniqueIdentifier {
 	}
 }
 
-// Value generates the global unique identifier based on the bank account number.
-func (g *GlobalUniqueIdentifier) Value(bankAccount string) (string, error) {
+// Validate checks that the bank account is a Bakong account ID that fits in its data object.
+func (g *GlobalUniqueIdentifier) Validate(bankAccount string) error {
+
+	// Ensure the bank account is a Bakong account ID of the form name@bank
+	if _, err := ParseAccountID(bankAccount); err != nil {
+		return err
+	}
 
 	// Ensure the bank account does not exceed the maximum allowed length
-	lengthOfBankAccount := len(bankAccount)
-	if lengthOfBankAccount > g.MaxLength {
-		return "", fmt.Errorf("bank account cannot exceed %d characters, your input length: %d characters", g.MaxLength, lengthOfBankAccount)
+	if len(bankAccount) > g.MaxLength {
+		return fmt.Errorf("bank account cannot exceed %d characters, your input length: %d characters", g.MaxLength, len(bankAccount))
 	}
+	return nil
+}
+
+// Value generates the global unique identifier based on the bank account number.
+func (g *GlobalUniqueIdentifier) Value(bankAccount string) (string, error) {
+	if err := g.Validate(bankAccount); err != nil {
+		return "", err
+	}
+	lengthOfBankAccount := len(bankAccount)
 
 	// Format the length of the bank account as two digits
 	lengthOfBankAccountStr := fmt.Sprintf("%02d", lengthOfBankAccount)
@@ -19,16 +19,24 @@ func NewMerchantCity(emv *EMV) *MerchantCity {
 	}
 }
 
-// Value generates and returns the formatted merchant city value
-func (m *MerchantCity) Value(merchantCity string) (string, error) {
+// Validate checks that the merchant city is not empty and fits in its data object
+func (m *MerchantCity) Validate(merchantCity string) error {
 	// Validate the merchant city
 	if merchantCity == "" {
-		return "", errors.New("merchant city cannot be empty")
+		return errors.New("merchant city cannot be empty")
 	}
 
 	// Ensure the merchant city does not exceed the maximum allowed length
 	if len(merchantCity) > m.MaxLength {
-		return "", fmt.Errorf("merchant city cannot exceed %d characters. Your input length: %d characters", m.MaxLength, len(merchantCity))
+		return fmt.Errorf("merchant city cannot exceed %d characters. Your input length: %d characters", m.MaxLength, len(merchantCity))
+	}
+	return nil
+}
+
+// Value generates and returns the formatted merchant city value
+func (m *MerchantCity) Value(merchantCity string) (string, error) {
+	if err := m.Validate(merchantCity); err != nil {
+		return "", err
 	}
 
 	// Calculate the length of the merchant city
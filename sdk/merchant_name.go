@@ -19,16 +19,24 @@ func NewMerchantName(emv *EMV) *MerchantName {
 	}
 }
 
-// Value generates and returns the formatted merchant name value
-func (m *MerchantName) Value(merchantName string) (string, error) {
+// Validate checks that the merchant name is not empty and fits in its data object
+func (m *MerchantName) Validate(merchantName string) error {
 	// Validate the merchant name
 	if merchantName == "" {
-		return "", errors.New("merchant name cannot be empty")
+		return errors.New("merchant name cannot be empty")
 	}
 
 	// Ensure the merchant name does not exceed the maximum allowed length
 	if len(merchantName) > m.MaxLength {
-		return "", fmt.Errorf("merchant name cannot exceed %d characters. Your input length: %d characters", m.MaxLength, len(merchantName))
+		return fmt.Errorf("merchant name cannot exceed %d characters. Your input length: %d characters", m.MaxLength, len(merchantName))
+	}
+	return nil
+}
+
+// Value generates and returns the formatted merchant name value
+func (m *MerchantName) Value(merchantName string) (string, error) {
+	if err := m.Validate(merchantName); err != nil {
+		return "", err
 	}
 
 	// Calculate the length of the merchant name
@@ -0,0 +1,83 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidPhoneNumber is returned for a number that is not a Cambodian mobile number.
+var ErrInvalidPhoneNumber = errors.New("invalid Cambodian mobile number")
+
+// cambodiaCallingCode is the country calling code of Cambodia.
+const cambodiaCallingCode = "855"
+
+// mobileOperators maps the two digit prefixes of Cambodian mobile numbers, without the trunk 0, to their operator.
+var mobileOperators = map[string]string{
+	"11": "Cellcard", "12": "Cellcard", "14": "Cellcard", "17": "Cellcard", "61": "Cellcard",
+	"76": "Cellcard", "77": "Cellcard", "78": "Cellcard", "85": "Cellcard", "89": "Cellcard",
+	"92": "Cellcard", "95": "Cellcard", "99": "Cellcard",
+	"10": "Smart", "15": "Smart", "16": "Smart", "69": "Smart", "70": "Smart",
+	"81": "Smart", "86": "Smart", "87": "Smart", "93": "Smart", "96": "Smart", "98": "Smart",
+	"31": "Metfone", "60": "Metfone", "66": "Metfone", "67": "Metfone", "68": "Metfone",
+	"71": "Metfone", "88": "Metfone", "90": "Metfone", "97": "Metfone",
+	"18": "Seatel",
+	"38": "Cootel",
+	"13": "qb", "80": "qb", "83": "qb", "84": "qb",
+}
+
+// PhoneNumber is a parsed Cambodian mobile number.
+type PhoneNumber struct {
+	National string // subscriber number without the trunk 0, e.g. "12345678"
+	Operator string // e.g. "Cellcard"
+}
+
+// ParsePhoneNumber parses a Cambodian mobile number in the local 012 345 678 form or the
+// international 855 12 345 678, +855 and 00855 forms. Spaces, dashes, dots and parentheses are ignored.
+func ParsePhoneNumber(number string) (PhoneNumber, error) {
+	digits := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, number)
+
+	switch {
+	case strings.HasPrefix(digits, "+"+cambodiaCallingCode):
+		digits = digits[len(cambodiaCallingCode)+1:]
+	case strings.HasPrefix(digits, "00"+cambodiaCallingCode):
+		digits = digits[len(cambodiaCallingCode)+2:]
+	case strings.HasPrefix(digits, cambodiaCallingCode):
+		digits = digits[len(cambodiaCallingCode):]
+	case strings.HasPrefix(digits, "0"):
+	default:
+		return PhoneNumber{}, fmt.Errorf("%w %q, expected 0xx, 855 or +855", ErrInvalidPhoneNumber, number)
+	}
+	// The trunk 0 is sometimes kept after the calling code, e.g. +855 012 345 678
+	digits = strings.TrimPrefix(digits, "0")
+
+	if !isDigits(digits) || len(digits) < 8 || len(digits) > 9 {
+		return PhoneNumber{}, fmt.Errorf("%w %q, expected 8 or 9 digits after the prefix", ErrInvalidPhoneNumber, number)
+	}
+	operator, ok := mobileOperators[digits[:2]]
+	if !ok {
+		return PhoneNumber{}, fmt.Errorf("%w %q, unknown operator prefix 0%s", ErrInvalidPhoneNumber, number, digits[:2])
+	}
+	return PhoneNumber{National: digits, Operator: operator}, nil
+}
+
+// String returns the canonical form encoded in QRs, e.g. "85512345678".
+func (p PhoneNumber) String() string {
+	return cambodiaCallingCode + p.National
+}
+
+// Local returns the number as dialled in Cambodia, e.g. "012345678".
+func (p PhoneNumber) Local() string {
+	return "0" + p.National
+}
+
+// E164 returns the number in international format, e.g. "+85512345678".
+func (p PhoneNumber) E164() string {
+	return "+" + p.String()
+}
@@ -0,0 +1,45 @@
+package sdk
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePhoneNumber(t *testing.T) {
+	tests := []struct {
+		number, want, operator string
+	}{
+		{"012345678", "85512345678", "Cellcard"},
+		{"012 345 678", "85512345678", "Cellcard"},
+		{"85512345678", "85512345678", "Cellcard"},
+		{"+855 12 345 678", "85512345678", "Cellcard"},
+		{"+855 (0)12-345-678", "85512345678", "Cellcard"},
+		{"0085512345678", "85512345678", "Cellcard"},
+		{"097 777 8888", "855977778888", "Metfone"},
+		{"+855.10.123.456", "85510123456", "Smart"},
+	}
+	for _, tc := range tests {
+		phone, err := ParsePhoneNumber(tc.number)
+		if err != nil || phone.String() != tc.want || phone.Operator != tc.operator {
+			t.Errorf("ParsePhoneNumber(%q) = %q %s, %v, want %q %s", tc.number, phone, phone.Operator, err, tc.want, tc.operator)
+		}
+	}
+
+	for _, number := range []string{"12345678", "0123456", "0123456789012", "+66812345678", "020123456", "01234567a"} {
+		if _, err := ParsePhoneNumber(number); !errors.Is(err, ErrInvalidPhoneNumber) {
+			t.Errorf("ParsePhoneNumber(%q) error = %v, want ErrInvalidPhoneNumber", number, err)
+		}
+	}
+}
+
+func TestPhoneNumberValueIsCanonical(t *testing.T) {
+	field := NewAdditionalDataField(NewEMV())
+	for _, number := range []string{"012 345 678", "+85512345678", "85512345678"} {
+		if got, err := field.PhoneNumberValue(number); err != nil || got != "021185512345678" {
+			t.Errorf("PhoneNumberValue(%q) = %q, %v", number, got, err)
+		}
+	}
+	if got, err := field.PhoneNumberValue(""); err != nil || got != "0200" {
+		t.Errorf("PhoneNumberValue(\"\") = %q, %v", got, err)
+	}
+}
@@ -21,8 +21,13 @@ func NewTimeStamp(emv *EMV) *TimeStamp {
 
 // Value generates the QR code data for the current timestamp
 func (t *TimeStamp) Value() string {
-	// Get the current timestamp in milliseconds
-	timestamp := fmt.Sprintf("%d", time.Now().UnixMilli())
+	return t.ValueAt(time.Now())
+}
+
+// ValueAt generates the QR code data for the given timestamp
+func (t *TimeStamp) ValueAt(at time.Time) string {
+	// Get the timestamp in milliseconds
+	timestamp := fmt.Sprintf("%d", at.UnixMilli())
 
 	// Format the length of the timestamp
 	lengthOfTimestamp := fmt.Sprintf("%02d", len(timestamp))
@@ -0,0 +1,45 @@
+package sdk
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// TLV holds a single tag-length-value data object of an EMV QR payload.
+type TLV struct {
+	Tag    string
+	Length int
+	Value  string
+}
+
+// String returns the TLV data object in its encoded form.
+func (t TLV) String() string {
+	return fmt.Sprintf("%s%02d%s", t.Tag, t.Length, t.Value)
+}
+
+// ParseTLV splits the data into its tag-length-value data objects without descending into templates.
+// On error the data objects read before it are returned along with the error.
+func ParseTLV(data string) ([]TLV, error) {
+	var result []TLV
+	for position := 0; position < len(data); {
+		// Every data object starts with a 2-digit tag and a 2-digit length
+		if len(data)-position < 4 {
+			return result, fmt.Errorf("truncated data object at position %d: %q", position, data[position:])
+		}
+		tag := data[position : position+2]
+		length, err := strconv.Atoi(data[position+2 : position+4])
+		if err != nil || !isNumeric(data[position+2:position+4]) {
+			return result, fmt.Errorf("invalid length %q for tag %s at position %d", data[position+2:position+4], tag, position)
+		}
+
+		// Ensure the value does not run past the end of the data
+		start := position + 4
+		if start+length > len(data) {
+			return result, fmt.Errorf("tag %s declares length %d but only %d characters remain", tag, length, len(data)-start)
+		}
+
+		result = append(result, TLV{Tag: tag, Length: length, Value: data[start : start+length]})
+		position = start + length
+	}
+	return result, nil
+}
@@ -10,6 +10,8 @@ type TransactionCurrency struct {
 	TransactionCurrency string
 	CurrencyUSD         string
 	CurrencyKHR         string
+	// Allowed lists the alphabetic codes accepted when generating a QR
+	Allowed []string
 }
 
 // NewTransactionCurrency initializes and returns a new TransactionCurrency instance
@@ -18,29 +20,43 @@ func NewTransactionCurrency(emv *EMV) *TransactionCurrency {
 		TransactionCurrency: emv.TransactionCurrency,
 		CurrencyUSD:         emv.TransactionCurrencyUSD,
 		CurrencyKHR:         emv.TransactionCurrencyKHR,
+		Allowed:             []string{"USD", "KHR"},
 	}
 }
 
-// Value generates the QR code data for the transaction currency
+// Resolve looks up the currency by its alphabetic or numeric code and checks that it is allowed
+func (tc *TransactionCurrency) Resolve(currency string) (Currency, error) {
+	// Look the currency up in the ISO 4217 table
+	found, ok := LookupCurrency(currency)
+	if !ok {
+		return Currency{}, fmt.Errorf("invalid currency code '%s', it is not an ISO 4217 currency", currency)
+	}
+	if !tc.IsAllowed(found.Code) {
+		return Currency{}, fmt.Errorf("currency '%s' is not allowed, supported codes are '%s'", found.Code, strings.Join(tc.Allowed, "', '"))
+	}
+	return found, nil
+}
+
+// Value generates the QR code data for the transaction currency, given its alphabetic or numeric code
 func (tc *TransactionCurrency) Value(currency string) (string, error) {
-	// Normalize the currency input
-	currency = strings.ToUpper(currency)
-
-	var currencyValue string
-
-	// Determine the currency value based on input
-	switch currency {
-	case "USD":
-		currencyValue = tc.CurrencyUSD
-	case "KHR":
-		currencyValue = tc.CurrencyKHR
-	default:
-		return "", fmt.Errorf("invalid currency code '%s', supported codes are 'USD' and 'KHR'", currency)
+	found, err := tc.Resolve(currency)
+	if err != nil {
+		return "", err
 	}
 
 	// Format the length of the currency value
-	lengthOfCurrency := fmt.Sprintf("%02d", len(currencyValue))
+	lengthOfCurrency := fmt.Sprintf("%02d", len(found.Numeric))
 
 	// Construct and return the formatted result
-	return fmt.Sprintf("%s%s%s", tc.TransactionCurrency, lengthOfCurrency, currencyValue), nil
+	return fmt.Sprintf("%s%s%s", tc.TransactionCurrency, lengthOfCurrency, found.Numeric), nil
+}
+
+// IsAllowed reports whether QRs may be generated in the currency
+func (tc *TransactionCurrency) IsAllowed(code string) bool {
+	for _, allowed := range tc.Allowed {
+		if strings.EqualFold(allowed, code) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// RequireAPIKey wraps the handler so every request must carry one of the keys, either as
+// "Authorization: Bearer <key>" or in the X-API-Key header. The health check and the OpenAPI
+// document stay public. Without the check, anyone who can reach the API uses the Bakong token it holds.
+func RequireAPIKey(next http.Handler, keys ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" || r.URL.Path == "/openapi.json" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !HasAPIKey(r, keys...) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="khqr"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid API key"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HasAPIKey reports whether the request carries one of the keys, e.g. for a stream.Authorizer
+// of a handler mounted next to RequireAPIKey.
+func HasAPIKey(r *http.Request, keys ...string) bool {
+	return validAPIKey(requestAPIKey(r), keys)
+}
+
+// requestAPIKey returns the bearer token of the request, or its X-API-Key header.
+func requestAPIKey(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// validAPIKey compares the key with every configured key in constant time.
+func validAPIKey(key string, keys []string) bool {
+	valid := false
+	for _, k := range keys {
+		if k != "" && subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
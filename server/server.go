@@ -0,0 +1,358 @@
+// Package server exposes KHQR operations as a JSON REST API so services
+// written in other languages can use them without holding the Bakong token.
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+// Limits applied to every request.
+const (
+	maxBodyBytes    = 64 << 10
+	maxBulkMD5Count = 50
+)
+
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// Server handles the KHQR REST API.
+type Server struct {
+	khqr *khqr.KHQR
+	mux  *http.ServeMux
+}
+
+// New initializes and returns a Server backed by the given KHQR instance.
+func New(k *khqr.KHQR) *Server {
+	s := &Server{khqr: k, mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST /v1/qr", s.handleCreateQR)
+	s.mux.HandleFunc("POST /v1/qr/convert", s.handleConvertQR)
+	s.mux.HandleFunc("POST /v1/qr/validate", s.handleValidateQR)
+	s.mux.HandleFunc("POST /v1/decode", s.handleDecode)
+	s.mux.HandleFunc("POST /v1/verify", s.handleVerify)
+	s.mux.HandleFunc("POST /v1/md5", s.handleMD5)
+	s.mux.HandleFunc("POST /v1/check", s.handleCheck)
+	s.mux.HandleFunc("POST /v1/check/bulk", s.handleCheckBulk)
+	s.mux.HandleFunc("POST /v1/deeplink", s.handleDeeplink)
+	s.mux.HandleFunc("GET /openapi.json", s.handleOpenAPI)
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	return s
+}
+
+// ServeHTTP dispatches the request to the matching endpoint.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	s.mux.ServeHTTP(w, r)
+}
+
+// NewHTTPServer returns an http.Server with conservative timeouts for serving the handler on addr.
+func NewHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		// Bakong calls can be slow, leave room for them before the response is cut off
+		WriteTimeout:   40 * time.Second,
+		IdleTimeout:    60 * time.Second,
+		MaxHeaderBytes: 16 << 10,
+	}
+}
+
+// CreateQRRequest is the body of POST /v1/qr.
+type CreateQRRequest struct {
+	BankAccount   string  `json:"bankAccount"`
+	MerchantName  string  `json:"merchantName"`
+	MerchantCity  string  `json:"merchantCity"`
+	Amount        float64 `json:"amount"`
+	Currency      string  `json:"currency"`
+	StoreLabel    string  `json:"storeLabel"`
+	PhoneNumber   string  `json:"phoneNumber"`
+	BillNumber    string  `json:"billNumber"`
+	TerminalLabel string  `json:"terminalLabel"`
+	Static        bool    `json:"static"`
+	// CreatedAt is encoded as the QR timestamp instead of the current time, optional.
+	CreatedAt time.Time `json:"createdAt,omitempty"`
+}
+
+// options returns the QR options of the request.
+func (req CreateQRRequest) options() khqr.QROptions {
+	return khqr.QROptions{
+		BankAccount:   req.BankAccount,
+		MerchantName:  req.MerchantName,
+		MerchantCity:  req.MerchantCity,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		StoreLabel:    req.StoreLabel,
+		PhoneNumber:   req.PhoneNumber,
+		BillNumber:    req.BillNumber,
+		TerminalLabel: req.TerminalLabel,
+		Static:        req.Static,
+		CreatedAt:     req.CreatedAt,
+	}
+}
+
+// CreateQRResponse is the body returned by POST /v1/qr.
+type CreateQRResponse struct {
+	QR  string `json:"qr"`
+	MD5 string `json:"md5"`
+}
+
+// ConvertQRRequest is the body of POST /v1/qr/convert.
+type ConvertQRRequest struct {
+	QR     string  `json:"qr"`
+	Amount float64 `json:"amount"`
+	// Currency defaults to the currency of the static QR.
+	Currency   string `json:"currency"`
+	BillNumber string `json:"billNumber"`
+}
+
+// QRRequest is the body of the endpoints that take a single QR code string.
+type QRRequest struct {
+	QR string `json:"qr"`
+}
+
+// VerifyResponse is the body returned by POST /v1/verify.
+type VerifyResponse struct {
+	Valid bool `json:"valid"`
+}
+
+// MD5Response is the body returned by POST /v1/md5.
+type MD5Response struct {
+	MD5 string `json:"md5"`
+}
+
+// CheckRequest is the body of POST /v1/check.
+type CheckRequest struct {
+	MD5 string `json:"md5"`
+}
+
+// CheckResponse is the body returned by POST /v1/check.
+type CheckResponse struct {
+	MD5    string `json:"md5"`
+	Status string `json:"status"`
+}
+
+// CheckBulkRequest is the body of POST /v1/check/bulk.
+type CheckBulkRequest struct {
+	MD5List []string `json:"md5List"`
+}
+
+// CheckBulkResponse is the body returned by POST /v1/check/bulk.
+type CheckBulkResponse struct {
+	Paid []string `json:"paid"`
+}
+
+// DeeplinkRequest is the body of POST /v1/deeplink.
+type DeeplinkRequest struct {
+	QR         string `json:"qr"`
+	Callback   string `json:"callback"`
+	AppIconURL string `json:"appIconUrl"`
+	AppName    string `json:"appName"`
+}
+
+// DeeplinkResponse is the body returned by POST /v1/deeplink.
+type DeeplinkResponse struct {
+	ShortLink string `json:"shortLink"`
+}
+
+// ValidateResponse is the body returned by POST /v1/qr/validate.
+type ValidateResponse struct {
+	Valid  bool               `json:"valid"`
+	Fields []*khqr.FieldError `json:"fields,omitempty"`
+}
+
+// ErrorResponse is the body returned with every non-2xx status.
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Fields lists every invalid field when the request failed validation.
+	Fields []*khqr.FieldError `json:"fields,omitempty"`
+}
+
+func (s *Server) handleCreateQR(w http.ResponseWriter, r *http.Request) {
+	var req CreateQRRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	qr, err := s.khqr.CreateQRWithOptions(req.options())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, CreateQRResponse{QR: qr, MD5: s.khqr.GenerateMD5(qr)})
+}
+
+func (s *Server) handleValidateQR(w http.ResponseWriter, r *http.Request) {
+	var req CreateQRRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	err := s.khqr.Validate(req.options())
+	var validation *khqr.ValidationError
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, ValidateResponse{Valid: true})
+	case errors.As(err, &validation):
+		writeJSON(w, http.StatusOK, ValidateResponse{Valid: false, Fields: validation.Fields})
+	default:
+		// Only a validation error says which fields are wrong, never report anything else as valid
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func (s *Server) handleConvertQR(w http.ResponseWriter, r *http.Request) {
+	var req ConvertQRRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.Currency == "" {
+		decoded, err := s.khqr.Decode(req.QR)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		req.Currency = decoded.TransactionCurrency
+	}
+	amount, err := sdk.MoneyFromFloat(req.Amount, req.Currency)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	qr, err := s.khqr.ConvertToDynamic(req.QR, amount, req.BillNumber)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, CreateQRResponse{QR: qr, MD5: s.khqr.GenerateMD5(qr)})
+}
+
+func (s *Server) handleDecode(w http.ResponseWriter, r *http.Request) {
+	var req QRRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	decoded, err := s.khqr.Decode(req.QR)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, decoded)
+}
+
+func (s *Server) handleVerify(w http.ResponseWriter, r *http.Request) {
+	var req QRRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	writeJSON(w, http.StatusOK, VerifyResponse{Valid: s.khqr.Verify(req.QR)})
+}
+
+func (s *Server) handleMD5(w http.ResponseWriter, r *http.Request) {
+	var req QRRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.QR == "" {
+		writeError(w, http.StatusBadRequest, errors.New("qr is required"))
+		return
+	}
+	writeJSON(w, http.StatusOK, MD5Response{MD5: s.khqr.GenerateMD5(req.QR)})
+}
+
+func (s *Server) handleCheck(w http.ResponseWriter, r *http.Request) {
+	var req CheckRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.MD5 == "" {
+		writeError(w, http.StatusBadRequest, errors.New("md5 is required"))
+		return
+	}
+	status, err := s.khqr.CheckPayment(req.MD5)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, CheckResponse{MD5: req.MD5, Status: status})
+}
+
+func (s *Server) handleCheckBulk(w http.ResponseWriter, r *http.Request) {
+	var req CheckBulkRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if len(req.MD5List) == 0 || len(req.MD5List) > maxBulkMD5Count {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("md5List must contain between 1 and %d hashes", maxBulkMD5Count))
+		return
+	}
+	paid, err := s.khqr.CheckBulkPayments(req.MD5List)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if paid == nil {
+		paid = []string{}
+	}
+	writeJSON(w, http.StatusOK, CheckBulkResponse{Paid: paid})
+}
+
+func (s *Server) handleDeeplink(w http.ResponseWriter, r *http.Request) {
+	var req DeeplinkRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if req.QR == "" {
+		writeError(w, http.StatusBadRequest, errors.New("qr is required"))
+		return
+	}
+	shortLink, err := s.khqr.GenerateDeeplink(req.QR, req.Callback, req.AppIconURL, req.AppName)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, DeeplinkResponse{ShortLink: shortLink})
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// decodeRequest reads a size-limited JSON body into v, writing a 400 response when it is invalid.
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as the JSON response body with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as an ErrorResponse with the given status.
+func writeError(w http.ResponseWriter, status int, err error) {
+	resp := ErrorResponse{Error: err.Error()}
+	var validation *khqr.ValidationError
+	if errors.As(err, &validation) {
+		resp.Fields = validation.Fields
+	}
+	writeJSON(w, status, resp)
+}
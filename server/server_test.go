@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	khqr "github.com/chhunneng/bakong-khqr"
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+func post(t *testing.T, handler http.Handler, path, body string, v interface{}) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("POST %s returned invalid JSON %q: %v", path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestCreateDecodeVerify(t *testing.T) {
+	handler := New(khqr.NewKHQR(""))
+
+	var created CreateQRResponse
+	code := post(t, handler, "/v1/qr", `{"bankAccount":"your_name@wing","merchantName":"Your Name","merchantCity":"Phnom Penh","amount":10000,"currency":"KHR","billNumber":"TRX019283775"}`, &created)
+	if code != http.StatusOK || created.QR == "" || len(created.MD5) != 32 {
+		t.Fatalf("POST /v1/qr = %d %+v", code, created)
+	}
+	qrBody, _ := json.Marshal(QRRequest{QR: created.QR})
+
+	var decoded sdk.DecodedQR
+	if code := post(t, handler, "/v1/decode", string(qrBody), &decoded); code != http.StatusOK || decoded.BillNumber != "TRX019283775" {
+		t.Errorf("POST /v1/decode = %d %+v", code, decoded)
+	}
+
+	var verified VerifyResponse
+	if code := post(t, handler, "/v1/verify", string(qrBody), &verified); code != http.StatusOK || !verified.Valid {
+		t.Errorf("POST /v1/verify = %d %+v", code, verified)
+	}
+
+	var hashed MD5Response
+	if code := post(t, handler, "/v1/md5", string(qrBody), &hashed); code != http.StatusOK || hashed.MD5 != created.MD5 {
+		t.Errorf("POST /v1/md5 = %d %+v", code, hashed)
+	}
+}
+
+func TestRejectsInvalidRequests(t *testing.T) {
+	handler := New(khqr.NewKHQR(""))
+	for _, tc := range []struct{ path, body string }{
+		{"/v1/qr", `{"bankAccount":"your_name@wing"}`},
+		{"/v1/qr", `{"unknownField":true}`},
+		{"/v1/qr/convert", `{"qr":"","amount":100}`},
+		{"/v1/decode", `not json`},
+		{"/v1/md5", `{}`},
+		{"/v1/check", `{}`},
+		{"/v1/check/bulk", `{"md5List":[]}`},
+	} {
+		var resp ErrorResponse
+		if code := post(t, handler, tc.path, tc.body, &resp); code != http.StatusBadRequest || resp.Error == "" {
+			t.Errorf("POST %s %s = %d %+v, want 400 with an error", tc.path, tc.body, code, resp)
+		}
+	}
+}
+
+func TestCreateQRReportsEveryInvalidField(t *testing.T) {
+	handler := New(khqr.NewKHQR(""))
+	body := `{"bankAccount":"your_name@wing","merchantName":"","merchantCity":"Phnom Penh","amount":-1,"currency":"USD"}`
+	var resp ErrorResponse
+	if code := post(t, handler, "/v1/qr", body, &resp); code != http.StatusBadRequest || len(resp.Fields) != 2 {
+		t.Fatalf("POST /v1/qr = %d %+v, want 400 with 2 fields", code, resp)
+	}
+	if resp.Fields[0].Field != "merchantName" || resp.Fields[0].Code != khqr.CodeRequired || resp.Fields[1].Code != khqr.CodeNegativeAmount {
+		t.Errorf("unexpected fields %+v %+v", resp.Fields[0], resp.Fields[1])
+	}
+
+	var validated ValidateResponse
+	if code := post(t, handler, "/v1/qr/validate", body, &validated); code != http.StatusOK || validated.Valid || len(validated.Fields) != 2 {
+		t.Errorf("POST /v1/qr/validate = %d %+v", code, validated)
+	}
+}
+
+func TestOpenAPIDocumentsEveryEndpoint(t *testing.T) {
+	rec := httptest.NewRecorder()
+	New(khqr.NewKHQR("")).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var doc struct {
+		Paths map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid OpenAPI document: %v", err)
+	}
+	for _, path := range []string{"/v1/qr", "/v1/qr/convert", "/v1/qr/validate", "/v1/decode", "/v1/verify", "/v1/md5", "/v1/check", "/v1/check/bulk", "/v1/deeplink"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("OpenAPI document is missing %s", path)
+		}
+	}
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	handler := RequireAPIKey(New(khqr.NewKHQR("")), "internal-secret")
+	body := `{"qr":"00020101021129180014your_name@wing5204599953038405802KH5909Your Name6010Phnom Penh6304B5E1"}`
+	for _, tc := range []struct {
+		header, value string
+		status        int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"Authorization", "Bearer wrong", http.StatusUnauthorized},
+		{"Authorization", "Bearer internal-secret", http.StatusOK},
+		{"X-API-Key", "internal-secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/md5", strings.NewReader(body))
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("POST /v1/md5 with %s %q = %d, want %d", tc.header, tc.value, rec.Code, tc.status)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /healthz without a key = %d, want 200", rec.Code)
+	}
+}
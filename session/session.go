@@ -0,0 +1,213 @@
+// Package session models a dynamic QR as a payment session with explicit states.
+//
+// A session starts Created, becomes Pending once it is shown to the payer
+// and ends Paid, Expired or Cancelled. Every transition is validated,
+// checked by the session's guards, timestamped and reported to its hooks exactly once.
+package session
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State is the state of a payment session.
+type State string
+
+// Session states.
+const (
+	Created   State = "CREATED"
+	Pending   State = "PENDING"
+	Paid      State = "PAID"
+	Expired   State = "EXPIRED"
+	Cancelled State = "CANCELLED"
+)
+
+// transitions lists the states each state may move to. Paid, Expired and Cancelled are final.
+var transitions = map[State][]State{
+	Created: {Pending, Expired, Cancelled},
+	Pending: {Paid, Expired, Cancelled},
+}
+
+// ErrInvalidTransition is returned when a session cannot move to the requested state.
+var ErrInvalidTransition = errors.New("session: invalid transition")
+
+// Final reports whether no transition leaves the state.
+func (s State) Final() bool {
+	return len(transitions[s]) == 0
+}
+
+// CanTransition reports whether a session may move from one state to another.
+func CanTransition(from, to State) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition is a recorded change of state.
+type Transition struct {
+	From   State
+	To     State
+	At     time.Time
+	Reason string
+}
+
+// Hook is called after every transition of a session.
+type Hook func(s *Session, t Transition)
+
+// Guard is called before a transition is applied. An error cancels the transition and no hook is called.
+// Guards run while the session is locked, so they must not call its methods.
+type Guard func(s *Session, t Transition) error
+
+// StaleError is returned by a guard when the session was already moved to State elsewhere,
+// e.g. by another process sharing the store. The session adopts the state without calling its hooks,
+// as they ran where the transition was made.
+type StaleError struct {
+	State State
+}
+
+func (e *StaleError) Error() string {
+	return fmt.Sprintf("session: already %s elsewhere", e.State)
+}
+
+// PaymentChecker reports the payment status of an md5. It is implemented by *khqr.KHQR.
+type PaymentChecker interface {
+	CheckPayment(md5 string) (string, error)
+}
+
+// Session is the payment of a single dynamic QR.
+type Session struct {
+	MD5       string
+	ExpiresAt time.Time // zero when the QR does not expire
+
+	mu      sync.Mutex
+	state   State
+	history []Transition
+	hooks   []Hook
+	guards  []Guard
+}
+
+// New initializes and returns a session in the Created state.
+func New(md5 string, expiresAt time.Time, hooks ...Hook) *Session {
+	return &Session{MD5: md5, ExpiresAt: expiresAt, state: Created, hooks: hooks}
+}
+
+// Restore returns a session already in the given state, e.g. when loading it from a store.
+// Hooks are only called for transitions made after the restore.
+func Restore(md5 string, expiresAt time.Time, state State, hooks ...Hook) *Session {
+	return &Session{MD5: md5, ExpiresAt: expiresAt, state: state, hooks: hooks}
+}
+
+// OnTransition adds a hook called after every later transition.
+func (s *Session) OnTransition(hook Hook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, hook)
+}
+
+// BeforeTransition adds a guard called before every later transition.
+func (s *Session) BeforeTransition(guard Guard) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.guards = append(s.guards, guard)
+}
+
+// State returns the current state.
+func (s *Session) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// History returns the transitions made so far, oldest first.
+func (s *Session) History() []Transition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Transition(nil), s.history...)
+}
+
+// Transition moves the session to the state, or returns ErrInvalidTransition or the error of a guard.
+func (s *Session) Transition(to State, at time.Time, reason string) error {
+	s.mu.Lock()
+	from := s.state
+	if !CanTransition(from, to) {
+		s.mu.Unlock()
+		return fmt.Errorf("%w from %s to %s", ErrInvalidTransition, from, to)
+	}
+	t := Transition{From: from, To: to, At: at, Reason: reason}
+	for _, guard := range s.guards {
+		if err := guard(s, t); err != nil {
+			var stale *StaleError
+			if errors.As(err, &stale) && stale.State != from {
+				s.state = stale.State
+				s.history = append(s.history, Transition{From: from, To: stale.State, At: at, Reason: "changed elsewhere"})
+			}
+			s.mu.Unlock()
+			return err
+		}
+	}
+	s.state = to
+	s.history = append(s.history, t)
+	hooks := append([]Hook(nil), s.hooks...)
+	s.mu.Unlock()
+
+	// Hooks run outside the lock so they may read the session
+	for _, hook := range hooks {
+		hook(s, t)
+	}
+	return nil
+}
+
+// Show marks a created session as pending once its QR is shown to the payer.
+func (s *Session) Show(at time.Time) error {
+	return s.Transition(Pending, at, "shown to payer")
+}
+
+// Cancel ends a session that has not been paid or expired.
+func (s *Session) Cancel(at time.Time, reason string) error {
+	return s.Transition(Cancelled, at, reason)
+}
+
+// Refresh checks the payment and the expiry of the session and applies the resulting transition.
+// Final sessions are returned as they are without calling the checker.
+func (s *Session) Refresh(checker PaymentChecker, now time.Time) (State, error) {
+	state := s.State()
+	if state.Final() {
+		return state, nil
+	}
+
+	// A payment that arrives just before the expiry is checked should still count
+	status, err := checker.CheckPayment(s.MD5)
+	if err != nil {
+		return state, err
+	}
+	if status == "PAID" {
+		if state == Created {
+			if err := s.Transition(Pending, now, "payment detected"); err != nil {
+				return s.State(), err
+			}
+		}
+		err := s.Transition(Paid, now, "payment confirmed by Bakong")
+		return s.State(), ignoreRace(err)
+	}
+
+	if !s.ExpiresAt.IsZero() && now.After(s.ExpiresAt) {
+		err := s.Transition(Expired, now, "QR expired unpaid")
+		return s.State(), ignoreRace(err)
+	}
+	return state, nil
+}
+
+// ignoreRace drops ErrInvalidTransition caused by another goroutine finishing the session first,
+// and the StaleError of another process doing so.
+func ignoreRace(err error) error {
+	var stale *StaleError
+	if errors.Is(err, ErrInvalidTransition) || errors.As(err, &stale) {
+		return nil
+	}
+	return err
+}
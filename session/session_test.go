@@ -0,0 +1,149 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chhunneng/bakong-khqr/store"
+)
+
+type fakeChecker struct {
+	status string
+	err    error
+	calls  int
+}
+
+func (f *fakeChecker) CheckPayment(md5 string) (string, error) {
+	f.calls++
+	return f.status, f.err
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	start := time.Now()
+	var seen []Transition
+	s := New("md5", start.Add(time.Minute), func(s *Session, t Transition) { seen = append(seen, t) })
+
+	if err := s.Show(start); err != nil {
+		t.Fatalf("Failed to show session: %v", err)
+	}
+	checker := &fakeChecker{status: "UNPAID"}
+	if state, err := s.Refresh(checker, start.Add(time.Second)); err != nil || state != Pending {
+		t.Fatalf("Refresh while unpaid = %s %v, want PENDING", state, err)
+	}
+
+	checker.status = "PAID"
+	if state, err := s.Refresh(checker, start.Add(2*time.Second)); err != nil || state != Paid {
+		t.Fatalf("Refresh after payment = %s %v, want PAID", state, err)
+	}
+
+	// Later refreshes must neither call Bakong nor report the payment again
+	calls := checker.calls
+	if state, _ := s.Refresh(checker, start.Add(time.Hour)); state != Paid || checker.calls != calls {
+		t.Errorf("Refresh of a paid session = %s after %d checks", state, checker.calls-calls)
+	}
+	if len(seen) != 2 || seen[0].To != Pending || seen[1].To != Paid || !seen[1].At.Equal(start.Add(2*time.Second)) {
+		t.Errorf("Hooks saw %+v", seen)
+	}
+	if len(s.History()) != 2 {
+		t.Errorf("History has %d transitions, want 2", len(s.History()))
+	}
+}
+
+func TestSessionExpiresUnpaid(t *testing.T) {
+	start := time.Now()
+	s := New("md5", start.Add(time.Minute))
+	checker := &fakeChecker{status: "UNPAID"}
+
+	if state, _ := s.Refresh(checker, start.Add(2*time.Minute)); state != Expired {
+		t.Fatalf("Refresh after expiry = %s, want EXPIRED", state)
+	}
+	checker.status = "PAID"
+	if state, _ := s.Refresh(checker, start.Add(3*time.Minute)); state != Expired {
+		t.Errorf("Expired session became %s", state)
+	}
+	if err := s.Transition(Paid, start, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Paying an expired session returned %v, want ErrInvalidTransition", err)
+	}
+}
+
+func TestSessionPaidJustAfterExpiryCounts(t *testing.T) {
+	start := time.Now()
+	s := New("md5", start)
+	if state, _ := s.Refresh(&fakeChecker{status: "PAID"}, start.Add(time.Second)); state != Paid {
+		t.Errorf("Refresh = %s, want PAID", state)
+	}
+}
+
+func TestSessionCheckErrorKeepsState(t *testing.T) {
+	s := New("md5", time.Time{})
+	state, err := s.Refresh(&fakeChecker{err: errors.New("bakong down")}, time.Now())
+	if err == nil || state != Created {
+		t.Errorf("Refresh with a failing checker = %s %v", state, err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	s := New("md5", time.Time{})
+	if err := s.Cancel(time.Now(), "order cancelled"); err != nil || s.State() != Cancelled {
+		t.Fatalf("Cancel = %v, state %s", err, s.State())
+	}
+	if err := s.Cancel(time.Now(), "again"); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Cancelling twice returned %v", err)
+	}
+}
+
+func TestPersist(t *testing.T) {
+	ctx := context.Background()
+	payments := store.NewMemoryStore()
+	start := time.Now()
+	payments.Save(ctx, store.Payment{MD5: "md5", Status: store.StatusUnpaid, CreatedAt: start, ExpiresAt: start.Add(time.Minute)})
+
+	stored, _ := payments.Get(ctx, "md5")
+	s := FromPayment(stored)
+	s.BeforeTransition(Persist(payments))
+	if s.State() != Pending {
+		t.Fatalf("Restored state = %s, want PENDING", s.State())
+	}
+	if _, err := s.Refresh(&fakeChecker{status: "PAID"}, start.Add(time.Second)); err != nil {
+		t.Fatalf("Persist failed: %v", err)
+	}
+
+	stored, _ = payments.Get(ctx, "md5")
+	if stored.Status != store.StatusPaid || !stored.UpdatedAt.Equal(start.Add(time.Second)) {
+		t.Errorf("Stored payment = %+v", stored)
+	}
+}
+
+func TestPersistAcrossProcesses(t *testing.T) {
+	ctx := context.Background()
+	payments := store.NewMemoryStore()
+	start := time.Now()
+	payments.Save(ctx, store.Payment{MD5: "md5", Status: store.StatusUnpaid, CreatedAt: start, ExpiresAt: start.Add(time.Minute)})
+
+	// Two processes restore the same payment, each with a hook that would e.g. ship the order
+	stored, _ := payments.Get(ctx, "md5")
+	var shipped int
+	ship := func(s *Session, t Transition) { shipped++ }
+	first, second := FromPayment(stored, ship), FromPayment(stored, ship)
+	first.BeforeTransition(Persist(payments))
+	second.BeforeTransition(Persist(payments))
+
+	if state, err := first.Refresh(&fakeChecker{status: "PAID"}, start.Add(time.Second)); err != nil || state != Paid {
+		t.Fatalf("First refresh = %s %v, want PAID", state, err)
+	}
+	// The second process finds the payment paid when expiring it, and adopts that state
+	if state, err := second.Refresh(&fakeChecker{status: "UNPAID"}, start.Add(2*time.Minute)); err != nil || state != Paid {
+		t.Errorf("Second refresh = %s %v, want PAID", state, err)
+	}
+	if err := second.Transition(Paid, start, ""); !errors.Is(err, ErrInvalidTransition) {
+		t.Errorf("Paying the adopted session again returned %v", err)
+	}
+	if shipped != 1 {
+		t.Errorf("Hooks ran %d times across processes, want 1", shipped)
+	}
+	if stored, _ := payments.Get(ctx, "md5"); stored.Status != store.StatusPaid {
+		t.Errorf("Stored status = %s, want PAID", stored.Status)
+	}
+}
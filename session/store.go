@@ -0,0 +1,63 @@
+package session
+
+import (
+	"context"
+	"errors"
+
+	"github.com/chhunneng/bakong-khqr/store"
+)
+
+// storeStatuses maps every state to the status recorded in a store.
+var storeStatuses = map[State]store.Status{
+	Created:   store.StatusUnpaid,
+	Pending:   store.StatusUnpaid,
+	Paid:      store.StatusPaid,
+	Expired:   store.StatusExpired,
+	Cancelled: store.StatusCancelled,
+}
+
+// StoreStatus returns the store status recorded for the state.
+func StoreStatus(state State) store.Status {
+	return storeStatuses[state]
+}
+
+// FromPayment restores the session of a stored payment. Unpaid payments were handed out, so they are Pending.
+func FromPayment(payment store.Payment, hooks ...Hook) *Session {
+	return Restore(payment.MD5, payment.ExpiresAt, stateOf(payment.Status), hooks...)
+}
+
+// stateOf returns the state of a stored status.
+func stateOf(status store.Status) State {
+	switch status {
+	case store.StatusPaid:
+		return Paid
+	case store.StatusExpired:
+		return Expired
+	case store.StatusCancelled:
+		return Cancelled
+	}
+	return Pending
+}
+
+// Persist returns a guard that records every transition in the store before the hooks run.
+// The status is only changed while it is still the one the session started from, so when
+// several processes share the store exactly one of them makes each transition and runs its hooks;
+// the others get a *StaleError and their sessions adopt the stored state.
+func Persist(payments store.Store) Guard {
+	return func(s *Session, t Transition) error {
+		from, to := StoreStatus(t.From), StoreStatus(t.To)
+		if from == to {
+			return nil
+		}
+		ctx := context.Background()
+		err := payments.SwapStatus(ctx, s.MD5, from, to, t.At)
+		if errors.Is(err, store.ErrStatusChanged) {
+			payment, getErr := payments.Get(ctx, s.MD5)
+			if getErr != nil {
+				return getErr
+			}
+			return &StaleError{State: stateOf(payment.Status)}
+		}
+		return err
+	}
+}
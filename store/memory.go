@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store kept in memory, for tests and single-process services.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	payments map[string]Payment
+}
+
+// NewMemoryStore initializes and returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{payments: make(map[string]Payment)}
+}
+
+// Save records a new payment.
+func (m *MemoryStore) Save(ctx context.Context, payment Payment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.payments[payment.MD5]; ok {
+		return ErrDuplicate
+	}
+	if payment.UpdatedAt.IsZero() {
+		payment.UpdatedAt = payment.CreatedAt
+	}
+	m.payments[payment.MD5] = payment
+	return nil
+}
+
+// Get returns the payment with the md5.
+func (m *MemoryStore) Get(ctx context.Context, md5 string) (Payment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	payment, ok := m.payments[md5]
+	if !ok {
+		return Payment{}, ErrNotFound
+	}
+	return payment, nil
+}
+
+// UpdateStatus sets the status of the payment with the md5.
+func (m *MemoryStore) UpdateStatus(ctx context.Context, md5 string, status Status, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	payment, ok := m.payments[md5]
+	if !ok {
+		return ErrNotFound
+	}
+	payment.Status = status
+	payment.UpdatedAt = at
+	m.payments[md5] = payment
+	return nil
+}
+
+// SwapStatus sets the status of the payment with the md5 while it is still from.
+func (m *MemoryStore) SwapStatus(ctx context.Context, md5 string, from, to Status, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	payment, ok := m.payments[md5]
+	if !ok {
+		return ErrNotFound
+	}
+	if payment.Status != from {
+		return ErrStatusChanged
+	}
+	payment.Status = to
+	payment.UpdatedAt = at
+	m.payments[md5] = payment
+	return nil
+}
+
+// List returns the payments matching the filter, oldest first.
+func (m *MemoryStore) List(ctx context.Context, filter Filter) ([]Payment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var result []Payment
+	for _, payment := range m.payments {
+		if filter.Status != "" && payment.Status != filter.Status {
+			continue
+		}
+		if !filter.CreatedAfter.IsZero() && !payment.CreatedAt.After(filter.CreatedAfter) {
+			continue
+		}
+		if !filter.CreatedBefore.IsZero() && !payment.CreatedAt.Before(filter.CreatedBefore) {
+			continue
+		}
+		result = append(result, payment)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].MD5 < result[j].MD5
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	now := time.Now()
+
+	first := Payment{MD5: "first", QR: "qr-1", Amount: "10000", Currency: "KHR", Status: StatusUnpaid, CreatedAt: now, ExpiresAt: now.Add(time.Minute)}
+	second := Payment{MD5: "second", QR: "qr-2", Currency: "USD", Status: StatusUnpaid, CreatedAt: now.Add(time.Second)}
+	for _, payment := range []Payment{second, first} {
+		if err := s.Save(ctx, payment); err != nil {
+			t.Fatalf("Failed to save %s: %v", payment.MD5, err)
+		}
+	}
+	if err := s.Save(ctx, first); err != ErrDuplicate {
+		t.Errorf("Saving a duplicate returned %v, want ErrDuplicate", err)
+	}
+
+	if err := s.UpdateStatus(ctx, "first", StatusPaid, now.Add(time.Minute)); err != nil {
+		t.Fatalf("Failed to update status: %v", err)
+	}
+	if err := s.UpdateStatus(ctx, "missing", StatusPaid, now); err != ErrNotFound {
+		t.Errorf("Updating a missing payment returned %v, want ErrNotFound", err)
+	}
+	if err := s.SwapStatus(ctx, "first", StatusUnpaid, StatusExpired, now); err != ErrStatusChanged {
+		t.Errorf("Expiring a paid payment returned %v, want ErrStatusChanged", err)
+	}
+
+	got, err := s.Get(ctx, "first")
+	if err != nil {
+		t.Fatalf("Failed to get payment: %v", err)
+	}
+	if got.Status != StatusPaid || !got.UpdatedAt.Equal(now.Add(time.Minute)) || got.QR != "qr-1" {
+		t.Errorf("Unexpected payment: %+v", got)
+	}
+	if _, err := s.Get(ctx, "missing"); err != ErrNotFound {
+		t.Errorf("Getting a missing payment returned %v, want ErrNotFound", err)
+	}
+
+	all, _ := s.List(ctx, Filter{})
+	if len(all) != 2 || all[0].MD5 != "first" || all[1].MD5 != "second" {
+		t.Errorf("List is not ordered by creation time: %+v", all)
+	}
+	unpaid, _ := s.List(ctx, Filter{Status: StatusUnpaid})
+	if len(unpaid) != 1 || unpaid[0].MD5 != "second" {
+		t.Errorf("Status filter returned %+v", unpaid)
+	}
+	limited, _ := s.List(ctx, Filter{CreatedBefore: now.Add(time.Hour), Limit: 1})
+	if len(limited) != 1 || limited[0].MD5 != "first" {
+		t.Errorf("Limit returned %+v", limited)
+	}
+}
+
+func TestPaymentExpired(t *testing.T) {
+	now := time.Now()
+	payment := Payment{Status: StatusUnpaid, ExpiresAt: now}
+	if payment.Expired(now) || !payment.Expired(now.Add(time.Millisecond)) {
+		t.Error("Unpaid payment expiry is wrong")
+	}
+	payment.Status = StatusPaid
+	if payment.Expired(now.Add(time.Hour)) {
+		t.Error("Paid payment expired")
+	}
+	if (Payment{Status: StatusUnpaid}).Expired(now) {
+		t.Error("Payment without expiry expired")
+	}
+}
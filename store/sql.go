@@ -0,0 +1,272 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect holds the SQL differences between the supported databases.
+type Dialect struct {
+	name        string
+	placeholder func(n int) string
+}
+
+// Supported SQL dialects.
+var (
+	Postgres = Dialect{
+		name:        "postgres",
+		placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+	}
+	SQLite = Dialect{
+		name:        "sqlite",
+		placeholder: func(n int) string { return "?" },
+	}
+	MySQL = Dialect{
+		name:        "mysql",
+		placeholder: func(n int) string { return "?" },
+	}
+)
+
+// String returns the name of the dialect.
+func (d Dialect) String() string {
+	return d.name
+}
+
+// migrations are applied in order and recorded in khqr_schema_migrations.
+// Append new migrations, never edit applied ones.
+var migrations = []string{
+	`CREATE TABLE khqr_payments (
+		md5         VARCHAR(32) NOT NULL PRIMARY KEY,
+		qr          TEXT NOT NULL,
+		amount      VARCHAR(32) NOT NULL,
+		currency    VARCHAR(3) NOT NULL,
+		bill_number VARCHAR(25) NOT NULL,
+		status      VARCHAR(16) NOT NULL,
+		created_at  BIGINT NOT NULL,
+		expires_at  BIGINT NOT NULL,
+		updated_at  BIGINT NOT NULL
+	)`,
+	`CREATE INDEX khqr_payments_status_created_at ON khqr_payments (status, created_at)`,
+}
+
+// SQLStore is a Store backed by a database/sql database.
+// Times are stored as Unix milliseconds so every driver round-trips them the same way.
+type SQLStore struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewSQLStore initializes and returns a SQLStore. Call Migrate before first use.
+func NewSQLStore(db *sql.DB, dialect Dialect) *SQLStore {
+	return &SQLStore{db: db, dialect: dialect}
+}
+
+// Migrate creates or upgrades the tables used by the store.
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS khqr_schema_migrations (version INTEGER NOT NULL PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("store: create migrations table: %w", err)
+	}
+
+	var applied int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM khqr_schema_migrations`).Scan(&applied); err != nil {
+		return fmt.Errorf("store: read schema version: %w", err)
+	}
+	for version := applied; version < len(migrations); version++ {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, migrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("store: apply migration %d: %w", version+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, s.query(`INSERT INTO khqr_schema_migrations (version) VALUES (?)`), version+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("store: record migration %d: %w", version+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save records a new payment.
+// A plain INSERT is used so errors such as a value too long for its column are never ignored;
+// when it fails, the md5 is looked up to tell a duplicate from any other error, as drivers word them differently.
+func (s *SQLStore) Save(ctx context.Context, payment Payment) error {
+	if payment.UpdatedAt.IsZero() {
+		payment.UpdatedAt = payment.CreatedAt
+	}
+	_, err := s.db.ExecContext(ctx, s.query(`INSERT INTO khqr_payments
+		(md5, qr, amount, currency, bill_number, status, created_at, expires_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		payment.MD5, payment.QR, payment.Amount, payment.Currency, payment.BillNumber, string(payment.Status),
+		toMillis(payment.CreatedAt), toMillis(payment.ExpiresAt), toMillis(payment.UpdatedAt))
+	if err != nil {
+		if exists, existsErr := s.exists(ctx, payment.MD5); existsErr == nil && exists {
+			return ErrDuplicate
+		}
+		return fmt.Errorf("store: save payment: %w", err)
+	}
+	return nil
+}
+
+// Get returns the payment with the md5.
+func (s *SQLStore) Get(ctx context.Context, md5 string) (Payment, error) {
+	row := s.db.QueryRowContext(ctx, s.query(`SELECT `+paymentColumns+` FROM khqr_payments WHERE md5 = ?`), md5)
+	payment, err := scanPayment(row)
+	if err == sql.ErrNoRows {
+		return Payment{}, ErrNotFound
+	}
+	if err != nil {
+		return Payment{}, fmt.Errorf("store: get payment: %w", err)
+	}
+	return payment, nil
+}
+
+// UpdateStatus sets the status of the payment with the md5.
+func (s *SQLStore) UpdateStatus(ctx context.Context, md5 string, status Status, at time.Time) error {
+	result, err := s.db.ExecContext(ctx, s.query(`UPDATE khqr_payments SET status = ?, updated_at = ? WHERE md5 = ?`),
+		string(status), toMillis(at), md5)
+	if err != nil {
+		return fmt.Errorf("store: update payment status: %w", err)
+	}
+	// MySQL counts only changed rows, so setting the same status again affects none
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		exists, err := s.exists(ctx, md5)
+		if err != nil {
+			return fmt.Errorf("store: update payment status: %w", err)
+		}
+		if !exists {
+			return ErrNotFound
+		}
+	}
+	return nil
+}
+
+// SwapStatus sets the status of the payment with the md5 while it is still from.
+// The condition is part of the UPDATE, so the database settles races between processes.
+func (s *SQLStore) SwapStatus(ctx context.Context, md5 string, from, to Status, at time.Time) error {
+	result, err := s.db.ExecContext(ctx, s.query(`UPDATE khqr_payments SET status = ?, updated_at = ? WHERE md5 = ? AND status = ?`),
+		string(to), toMillis(at), md5, string(from))
+	if err != nil {
+		return fmt.Errorf("store: swap payment status: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows > 0 {
+		return nil
+	}
+	// Tell a missing payment from one in another status, and from an unchanged row MySQL does not count
+	payment, err := s.Get(ctx, md5)
+	if err != nil {
+		return err
+	}
+	if payment.Status == from && from == to {
+		return nil
+	}
+	return ErrStatusChanged
+}
+
+// exists reports whether a payment with the md5 is stored.
+func (s *SQLStore) exists(ctx context.Context, md5 string) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, s.query(`SELECT COUNT(*) FROM khqr_payments WHERE md5 = ?`), md5).Scan(&n)
+	return n > 0, err
+}
+
+// List returns the payments matching the filter, oldest first.
+func (s *SQLStore) List(ctx context.Context, filter Filter) ([]Payment, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, string(filter.Status))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at > ?")
+		args = append(args, toMillis(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, toMillis(filter.CreatedBefore))
+	}
+
+	query := `SELECT ` + paymentColumns + ` FROM khqr_payments`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at, md5"
+	if filter.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.query(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("store: list payments: %w", err)
+	}
+	defer rows.Close()
+
+	var result []Payment
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("store: list payments: %w", err)
+		}
+		result = append(result, payment)
+	}
+	return result, rows.Err()
+}
+
+// paymentColumns lists the columns read by scanPayment, in order.
+const paymentColumns = `md5, qr, amount, currency, bill_number, status, created_at, expires_at, updated_at`
+
+// scanPayment reads a row selected with paymentColumns.
+func scanPayment(row interface{ Scan(...interface{}) error }) (Payment, error) {
+	var payment Payment
+	var status string
+	var createdAt, expiresAt, updatedAt int64
+	err := row.Scan(&payment.MD5, &payment.QR, &payment.Amount, &payment.Currency, &payment.BillNumber, &status, &createdAt, &expiresAt, &updatedAt)
+	if err != nil {
+		return Payment{}, err
+	}
+	payment.Status = Status(status)
+	payment.CreatedAt = fromMillis(createdAt)
+	payment.ExpiresAt = fromMillis(expiresAt)
+	payment.UpdatedAt = fromMillis(updatedAt)
+	return payment, nil
+}
+
+// query rewrites the ? placeholders of a query for the store's dialect.
+func (s *SQLStore) query(query string) string {
+	var sb strings.Builder
+	n := 0
+	for _, ch := range query {
+		if ch == '?' {
+			n++
+			sb.WriteString(s.dialect.placeholder(n))
+			continue
+		}
+		sb.WriteRune(ch)
+	}
+	return sb.String()
+}
+
+// toMillis converts a time to Unix milliseconds, keeping the zero time as 0.
+func toMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// fromMillis converts Unix milliseconds back to a time, keeping 0 as the zero time.
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newMockStore(t *testing.T, dialect Dialect) (*SQLStore, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return NewSQLStore(db, dialect), mock
+}
+
+func TestSQLStoreMigrate(t *testing.T) {
+	s, mock := newMockStore(t, Postgres)
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS khqr_schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	// The first migration is already applied, only the second runs
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM khqr_schema_migrations`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE INDEX khqr_payments_status_created_at`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO khqr_schema_migrations (version) VALUES ($1)`)).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := s.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSQLStoreMigrateRollsBack(t *testing.T) {
+	s, mock := newMockStore(t, SQLite)
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS khqr_schema_migrations`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM khqr_schema_migrations`)).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE khqr_payments`)).WillReturnError(errors.New("permission denied"))
+	mock.ExpectRollback()
+
+	if err := s.Migrate(context.Background()); err == nil {
+		t.Error("Migrate ignored a failed migration")
+	}
+}
+
+func TestSQLStoreSave(t *testing.T) {
+	ctx := context.Background()
+	createdAt := time.UnixMilli(1714555800000).UTC()
+	payment := Payment{MD5: "first", QR: "qr-1", Amount: "10000", Currency: "KHR", Status: StatusUnpaid, CreatedAt: createdAt}
+	insert := regexp.QuoteMeta(`INSERT INTO khqr_payments`)
+	exists := regexp.QuoteMeta(`SELECT COUNT(*) FROM khqr_payments WHERE md5 = $1`)
+
+	s, mock := newMockStore(t, Postgres)
+	// Placeholders are numbered for Postgres and the zero expiry is stored as 0
+	mock.ExpectExec(regexp.QuoteMeta(`VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)).
+		WithArgs("first", "qr-1", "10000", "KHR", "", "UNPAID", int64(1714555800000), int64(0), int64(1714555800000)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := s.Save(ctx, payment); err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed insert of a stored md5 is a duplicate
+	mock.ExpectExec(insert).WillReturnError(errors.New("pq: duplicate key value violates unique constraint"))
+	mock.ExpectQuery(exists).WithArgs("first").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	if err := s.Save(ctx, payment); err != ErrDuplicate {
+		t.Errorf("Saving a duplicate returned %v, want ErrDuplicate", err)
+	}
+
+	// Any other failure is returned as is
+	failure := errors.New("value too long for type character varying(25)")
+	mock.ExpectExec(insert).WillReturnError(failure)
+	mock.ExpectQuery(exists).WithArgs("first").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	if err := s.Save(ctx, payment); !errors.Is(err, failure) {
+		t.Errorf("Saving a payment the database rejects returned %v, want the database error", err)
+	}
+}
+
+func TestSQLStoreGet(t *testing.T) {
+	s, mock := newMockStore(t, MySQL)
+	columns := []string{"md5", "qr", "amount", "currency", "bill_number", "status", "created_at", "expires_at", "updated_at"}
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM khqr_payments WHERE md5 = ?`)).WithArgs("first").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("first", "qr-1", "10000", "KHR", "INV-1", "PAID", int64(1714555800000), int64(0), int64(1714555860000)))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM khqr_payments WHERE md5 = ?`)).WithArgs("missing").WillReturnRows(sqlmock.NewRows(columns))
+
+	got, err := s.Get(context.Background(), "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusPaid || got.BillNumber != "INV-1" || !got.ExpiresAt.IsZero() || got.UpdatedAt.UnixMilli() != 1714555860000 {
+		t.Errorf("Unexpected payment: %+v", got)
+	}
+	if _, err := s.Get(context.Background(), "missing"); err != ErrNotFound {
+		t.Errorf("Getting a missing payment returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestSQLStoreUpdateStatus(t *testing.T) {
+	ctx := context.Background()
+	at := time.UnixMilli(1714555860000)
+	update := regexp.QuoteMeta(`UPDATE khqr_payments SET status = ?, updated_at = ? WHERE md5 = ?`)
+	exists := regexp.QuoteMeta(`SELECT COUNT(*) FROM khqr_payments WHERE md5 = ?`)
+
+	s, mock := newMockStore(t, MySQL)
+	mock.ExpectExec(update).WithArgs("PAID", int64(1714555860000), "first").WillReturnResult(sqlmock.NewResult(0, 1))
+	// MySQL reports no affected rows when the status is unchanged
+	mock.ExpectExec(update).WithArgs("PAID", int64(1714555860000), "first").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(exists).WithArgs("first").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectExec(update).WithArgs("PAID", int64(1714555860000), "missing").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(exists).WithArgs("missing").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	if err := s.UpdateStatus(ctx, "first", StatusPaid, at); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateStatus(ctx, "first", StatusPaid, at); err != nil {
+		t.Errorf("Setting the same status again returned %v", err)
+	}
+	if err := s.UpdateStatus(ctx, "missing", StatusPaid, at); err != ErrNotFound {
+		t.Errorf("Updating a missing payment returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestSQLStoreList(t *testing.T) {
+	s, mock := newMockStore(t, Postgres)
+	createdAfter := time.UnixMilli(1714555800000)
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM khqr_payments WHERE status = $1 AND created_at > $2 ORDER BY created_at, md5 LIMIT 10`)).
+		WithArgs("UNPAID", int64(1714555800000)).
+		WillReturnRows(sqlmock.NewRows([]string{"md5", "qr", "amount", "currency", "bill_number", "status", "created_at", "expires_at", "updated_at"}).
+			AddRow("second", "qr-2", "", "USD", "", "UNPAID", int64(1714555801000), int64(0), int64(1714555801000)))
+
+	payments, err := s.List(context.Background(), Filter{Status: StatusUnpaid, CreatedAfter: createdAfter, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != 1 || payments[0].MD5 != "second" {
+		t.Errorf("Unexpected payments: %+v", payments)
+	}
+}
+
+func TestSQLStoreSwapStatus(t *testing.T) {
+	ctx := context.Background()
+	at := time.UnixMilli(1714555860000)
+	swap := regexp.QuoteMeta(`UPDATE khqr_payments SET status = $1, updated_at = $2 WHERE md5 = $3 AND status = $4`)
+	get := regexp.QuoteMeta(`FROM khqr_payments WHERE md5 = $1`)
+	columns := []string{"md5", "qr", "amount", "currency", "bill_number", "status", "created_at", "expires_at", "updated_at"}
+
+	s, mock := newMockStore(t, Postgres)
+	mock.ExpectExec(swap).WithArgs("PAID", int64(1714555860000), "first", "UNPAID").WillReturnResult(sqlmock.NewResult(0, 1))
+	// Another process expired the payment first
+	mock.ExpectExec(swap).WithArgs("PAID", int64(1714555860000), "second", "UNPAID").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(get).WithArgs("second").
+		WillReturnRows(sqlmock.NewRows(columns).AddRow("second", "qr-2", "", "USD", "", "EXPIRED", int64(1714555800000), int64(0), int64(1714555800000)))
+	mock.ExpectExec(swap).WithArgs("PAID", int64(1714555860000), "missing", "UNPAID").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(get).WithArgs("missing").WillReturnRows(sqlmock.NewRows(columns))
+
+	if err := s.SwapStatus(ctx, "first", StatusUnpaid, StatusPaid, at); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SwapStatus(ctx, "second", StatusUnpaid, StatusPaid, at); err != ErrStatusChanged {
+		t.Errorf("Swapping a changed status returned %v, want ErrStatusChanged", err)
+	}
+	if err := s.SwapStatus(ctx, "missing", StatusUnpaid, StatusPaid, at); err != ErrNotFound {
+		t.Errorf("Swapping a missing payment returned %v, want ErrNotFound", err)
+	}
+}
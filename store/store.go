@@ -0,0 +1,68 @@
+// Package store keeps a record of every generated QR and its payment status
+// so watchers, reconciliation jobs and restarted services share one source of truth.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Status is the payment status of a stored QR.
+type Status string
+
+// Payment statuses. PAID and UNPAID match the values returned by KHQR.CheckPayment.
+const (
+	StatusUnpaid    Status = "UNPAID"
+	StatusPaid      Status = "PAID"
+	StatusExpired   Status = "EXPIRED"
+	StatusCancelled Status = "CANCELLED"
+)
+
+// Errors returned by every Store implementation.
+var (
+	ErrNotFound      = errors.New("store: payment not found")
+	ErrDuplicate     = errors.New("store: payment already exists")
+	ErrStatusChanged = errors.New("store: payment status changed")
+)
+
+// Payment is a generated QR and what is known about its payment.
+type Payment struct {
+	MD5        string
+	QR         string
+	Amount     string // exactly as encoded in the QR, empty for static QRs
+	Currency   string
+	BillNumber string
+	Status     Status
+	CreatedAt  time.Time
+	ExpiresAt  time.Time // zero when the QR does not expire
+	UpdatedAt  time.Time
+}
+
+// Expired reports whether the payment is still unpaid after its expiry time.
+func (p Payment) Expired(now time.Time) bool {
+	return p.Status == StatusUnpaid && !p.ExpiresAt.IsZero() && now.After(p.ExpiresAt)
+}
+
+// Filter selects payments in List. Zero fields match everything.
+type Filter struct {
+	Status        Status
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Limit         int
+}
+
+// Store records generated QRs and their payment status.
+type Store interface {
+	// Save records a new payment, returning ErrDuplicate if its md5 is already stored.
+	Save(ctx context.Context, payment Payment) error
+	// Get returns the payment with the md5, or ErrNotFound.
+	Get(ctx context.Context, md5 string) (Payment, error)
+	// UpdateStatus sets the status of the payment with the md5, or returns ErrNotFound.
+	UpdateStatus(ctx context.Context, md5 string, status Status, at time.Time) error
+	// SwapStatus sets the status of the payment with the md5 only while it is still from,
+	// returning ErrStatusChanged otherwise, so two processes can never both make the same transition.
+	SwapStatus(ctx context.Context, md5 string, from, to Status, at time.Time) error
+	// List returns the payments matching the filter, oldest first.
+	List(ctx context.Context, filter Filter) ([]Payment, error)
+}
@@ -0,0 +1,67 @@
+package khqr
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chhunneng/bakong-khqr/store"
+)
+
+func TestCreateQRRecordsInStore(t *testing.T) {
+	khqrInstance := NewKHQR("")
+	payments := store.NewMemoryStore()
+	khqrInstance.SetStore(payments, 15*time.Minute)
+
+	qr, err := khqrInstance.CreateQR("your_name@wing", "Your Name", "Phnom Penh", 10000, "KHR", "MShop", "85512345678", "TRX019283775", "Cashier-01", false)
+	if err != nil {
+		t.Fatalf("Failed to create QR: %v", err)
+	}
+
+	payment, err := payments.Get(context.Background(), khqrInstance.GenerateMD5(qr))
+	if err != nil {
+		t.Fatalf("QR was not recorded: %v", err)
+	}
+	if payment.QR != qr || payment.Currency != "KHR" || payment.BillNumber != "TRX019283775" || payment.Status != store.StatusUnpaid {
+		t.Errorf("Unexpected payment: %+v", payment)
+	}
+	if lifetime := payment.ExpiresAt.Sub(payment.CreatedAt); lifetime != 15*time.Minute {
+		t.Errorf("Payment expires after %v, want 15m", lifetime)
+	}
+}
+
+func TestCreateQRRecordsCreationTime(t *testing.T) {
+	khqrInstance := NewKHQR("")
+	payments := store.NewMemoryStore()
+	khqrInstance.SetStore(payments, time.Minute)
+	createdAt := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	khqrInstance.SetClock(FixedClock(createdAt))
+
+	qr, err := khqrInstance.CreateQR("your_name@wing", "Your Name", "Phnom Penh", 5, "USD", "", "", "", "", false)
+	if err != nil {
+		t.Fatalf("Failed to create QR: %v", err)
+	}
+	payment, err := payments.Get(context.Background(), khqrInstance.GenerateMD5(qr))
+	if err != nil {
+		t.Fatalf("QR was not recorded: %v", err)
+	}
+	if !payment.CreatedAt.Equal(createdAt) || !payment.ExpiresAt.Equal(createdAt.Add(time.Minute)) {
+		t.Errorf("Payment created at %v and expires at %v", payment.CreatedAt, payment.ExpiresAt)
+	}
+}
+
+func TestCreateQRTwiceWithFixedClock(t *testing.T) {
+	khqrInstance := NewKHQR("")
+	payments := store.NewMemoryStore()
+	khqrInstance.SetStore(payments, time.Minute)
+	khqrInstance.SetClock(FixedClock(time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)))
+
+	first, err := khqrInstance.CreateQR("your_name@wing", "Your Name", "Phnom Penh", 5, "USD", "", "", "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := khqrInstance.CreateQR("your_name@wing", "Your Name", "Phnom Penh", 5, "USD", "", "", "", "", false)
+	if err != nil || second != first {
+		t.Errorf("Creating the same QR again = %q, %v, want the first QR", second, err)
+	}
+}
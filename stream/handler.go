@@ -0,0 +1,164 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/chhunneng/bakong-khqr/store"
+)
+
+// Limits of the long-poll and SSE responses.
+const (
+	DefaultWait       = 25 * time.Second
+	MaxWait           = 60 * time.Second
+	heartbeatInterval = 15 * time.Second
+)
+
+var md5Pattern = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+// StatusEvent is the JSON sent for every status update.
+type StatusEvent struct {
+	MD5    string `json:"md5"`
+	Status string `json:"status"`
+}
+
+// Authorizer decides whether a request may watch the md5. Every watched md5 is checked with
+// the Bakong token, so a handler open to the internet must not accept arbitrary md5s.
+type Authorizer func(r *http.Request, md5 string) bool
+
+// KnownPayments returns an Authorizer accepting only the md5s of payments recorded in the store.
+func KnownPayments(payments store.Store) Authorizer {
+	return func(r *http.Request, md5 string) bool {
+		_, err := payments.Get(r.Context(), md5)
+		return err == nil
+	}
+}
+
+// AllowAll is an Authorizer accepting every md5, for handlers already behind authentication such as an API key.
+func AllowAll(r *http.Request, md5 string) bool {
+	return true
+}
+
+// Handler serves the payment status of an md5 taken from the {md5} path value or the md5 query parameter.
+//
+// Requests accepting text/event-stream get a "status" event now and on every change until the payment is paid.
+// Other requests long-poll: the response is sent as soon as the status differs from the status query
+// parameter, or after the wait query parameter (default 25s, at most 60s) with the unchanged status.
+// Md5s the authorizer rejects get a 404 without reaching the poller.
+type Handler struct {
+	poller    *Poller
+	authorize Authorizer
+}
+
+// NewHandler initializes and returns a Handler backed by the shared poller, serving the md5s the authorizer accepts.
+func NewHandler(poller *Poller, authorize Authorizer) *Handler {
+	return &Handler{poller: poller, authorize: authorize}
+}
+
+// ServeHTTP streams or long-polls the payment status.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	md5 := r.PathValue("md5")
+	if md5 == "" {
+		md5 = r.URL.Query().Get("md5")
+	}
+	if !md5Pattern.MatchString(md5) {
+		http.Error(w, "a 32 character hexadecimal md5 is required", http.StatusBadRequest)
+		return
+	}
+	md5 = strings.ToLower(md5)
+	if !h.authorize(r, md5) {
+		http.Error(w, "unknown payment", http.StatusNotFound)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		h.serveEvents(w, r, md5)
+		return
+	}
+	h.serveLongPoll(w, r, md5)
+}
+
+// serveEvents streams status events until the payment is paid or the client goes away.
+func (h *Handler) serveEvents(w http.ResponseWriter, r *http.Request, md5 string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	status, changed, unsubscribe := h.poller.Subscribe(md5)
+	defer func() { unsubscribe() }()
+
+	// Streams outlive the write timeout of the server, so lift it for this response
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	// Ask browsers to wait a poll interval before reconnecting
+	fmt.Fprintf(w, "retry: %d\n\n", h.poller.interval.Milliseconds())
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	send := true
+	for {
+		if send {
+			data, _ := json.Marshal(StatusEvent{MD5: md5, Status: status})
+			fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+			flusher.Flush()
+			if status == StatusPaid {
+				return
+			}
+		}
+
+		send = false
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case <-changed:
+			unsubscribe()
+			status, changed, unsubscribe = h.poller.Subscribe(md5)
+			send = true
+		}
+	}
+}
+
+// serveLongPoll answers once the status differs from the client's or the wait runs out.
+func (h *Handler) serveLongPoll(w http.ResponseWriter, r *http.Request, md5 string) {
+	wait := DefaultWait
+	if value := r.URL.Query().Get("wait"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			http.Error(w, "wait must be a duration such as 30s", http.StatusBadRequest)
+			return
+		}
+		wait = min(parsed, MaxWait)
+	}
+	known := r.URL.Query().Get("status")
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + 10*time.Second))
+
+	status, changed, unsubscribe := h.poller.Subscribe(md5)
+	defer func() { unsubscribe() }()
+	if known == status || (known == "" && status != StatusPaid) {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-r.Context().Done():
+			return
+		case <-timer.C:
+		case <-changed:
+			unsubscribe()
+			status, _, unsubscribe = h.poller.Subscribe(md5)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(StatusEvent{MD5: md5, Status: status})
+}
@@ -0,0 +1,160 @@
+// Package stream pushes payment status to browser checkout pages over
+// Server-Sent Events, with a long-poll fallback.
+//
+// All handlers share one Poller, which checks every watched md5 with a single
+// bulk request per interval no matter how many pages are open.
+package stream
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Payment statuses reported to subscribers, matching KHQR.CheckPayment.
+const (
+	StatusPaid   = "PAID"
+	StatusUnpaid = "UNPAID"
+)
+
+// Defaults used by NewPoller.
+const (
+	DefaultInterval = 3 * time.Second
+	maxBulkMD5Count = 50
+	paidRetention   = 10 * time.Minute
+)
+
+// PaymentChecker reports which md5s are paid. It is implemented by *khqr.KHQR.
+type PaymentChecker interface {
+	CheckBulkPayments(md5List []string) ([]string, error)
+}
+
+// watch is the shared state of one md5.
+type watch struct {
+	status      string
+	subscribers int
+	changed     chan struct{} // closed when status changes
+	paidAt      time.Time
+}
+
+// Poller checks the payment status of every subscribed md5.
+type Poller struct {
+	checker  PaymentChecker
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	watches map[string]*watch
+	onPaid  []func(md5 string, at time.Time)
+}
+
+// NewPoller initializes and returns a Poller checking every interval, or DefaultInterval when it is zero.
+func NewPoller(checker PaymentChecker, interval time.Duration) *Poller {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Poller{
+		checker:  checker,
+		interval: interval,
+		now:      time.Now,
+		watches:  make(map[string]*watch),
+	}
+}
+
+// Subscribe starts watching the md5 and returns its current status and a channel closed on the next change.
+// Call unsubscribe once the status is no longer needed.
+func (p *Poller) Subscribe(md5 string) (status string, changed <-chan struct{}, unsubscribe func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	w, ok := p.watches[md5]
+	if !ok {
+		w = &watch{status: StatusUnpaid, changed: make(chan struct{})}
+		p.watches[md5] = w
+	}
+	w.subscribers++
+
+	var once sync.Once
+	unsubscribe = func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			w.subscribers--
+		})
+	}
+	return w.status, w.changed, unsubscribe
+}
+
+// OnPaid adds a function called once for every md5 the poller finds paid, e.g. to record the payment in a store.
+func (p *Poller) OnPaid(fn func(md5 string, at time.Time)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onPaid = append(p.onPaid, fn)
+}
+
+// Run polls every interval until the context is cancelled.
+func (p *Poller) Run(ctx context.Context) error {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.Poll()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks every unpaid md5 that has subscribers once and notifies the subscribers of paid ones.
+// It returns the error of the last failed bulk check, if any.
+func (p *Poller) Poll() error {
+	now := p.now()
+	p.mu.Lock()
+	var md5List []string
+	for md5, w := range p.watches {
+		switch {
+		case w.status == StatusPaid && now.Sub(w.paidAt) > paidRetention:
+			// Paid results are kept for a while so late page loads do not hit Bakong
+			delete(p.watches, md5)
+		case w.status == StatusPaid:
+		case w.subscribers <= 0:
+			delete(p.watches, md5)
+		default:
+			md5List = append(md5List, md5)
+		}
+	}
+	p.mu.Unlock()
+
+	var lastErr error
+	for start := 0; start < len(md5List); start += maxBulkMD5Count {
+		end := min(start+maxBulkMD5Count, len(md5List))
+		paid, err := p.checker.CheckBulkPayments(md5List[start:end])
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		var newlyPaid []string
+		p.mu.Lock()
+		for _, md5 := range paid {
+			if w, ok := p.watches[md5]; ok && w.status != StatusPaid {
+				w.status = StatusPaid
+				w.paidAt = now
+				close(w.changed)
+				w.changed = make(chan struct{})
+				newlyPaid = append(newlyPaid, md5)
+			}
+		}
+		onPaid := append([]func(md5 string, at time.Time){}, p.onPaid...)
+		p.mu.Unlock()
+
+		// Callbacks run outside the lock so they may subscribe
+		for _, md5 := range newlyPaid {
+			for _, fn := range onPaid {
+				fn(md5, now)
+			}
+		}
+	}
+	return lastErr
+}
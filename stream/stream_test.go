@@ -0,0 +1,200 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chhunneng/bakong-khqr/store"
+)
+
+const testMD5 = "dfcabf4598d1c405a75540a3d4ca099d"
+
+type countingChecker struct {
+	mu    sync.Mutex
+	paid  map[string]bool
+	calls int
+}
+
+func (c *countingChecker) CheckBulkPayments(md5List []string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	var paid []string
+	for _, md5 := range md5List {
+		if c.paid[md5] {
+			paid = append(paid, md5)
+		}
+	}
+	return paid, nil
+}
+
+func (c *countingChecker) pay(md5 string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.paid[md5] = true
+}
+
+func TestPollerSharesOneCheckBetweenSubscribers(t *testing.T) {
+	checker := &countingChecker{paid: map[string]bool{}}
+	poller := NewPoller(checker, time.Second)
+
+	var channels []<-chan struct{}
+	for i := 0; i < 10; i++ {
+		_, changed, unsubscribe := poller.Subscribe(testMD5)
+		defer unsubscribe()
+		channels = append(channels, changed)
+	}
+	poller.Poll()
+	checker.pay(testMD5)
+	poller.Poll()
+
+	if checker.calls != 2 {
+		t.Errorf("Made %d Bakong calls for 10 subscribers over 2 polls, want 2", checker.calls)
+	}
+	for i, changed := range channels {
+		select {
+		case <-changed:
+		default:
+			t.Errorf("Subscriber %d was not notified", i)
+		}
+	}
+	if status, _, unsubscribe := poller.Subscribe(testMD5); status != StatusPaid {
+		t.Errorf("Late subscriber saw %s, want PAID", status)
+	} else {
+		unsubscribe()
+	}
+
+	// Paid md5s are remembered, so later polls do not check them again
+	poller.Poll()
+	if checker.calls != 2 {
+		t.Errorf("Paid md5 was checked again")
+	}
+}
+
+func TestPollerForgetsUnsubscribedMD5s(t *testing.T) {
+	checker := &countingChecker{paid: map[string]bool{}}
+	poller := NewPoller(checker, time.Second)
+	_, _, unsubscribe := poller.Subscribe(testMD5)
+	unsubscribe()
+	poller.Poll()
+	if checker.calls != 0 {
+		t.Errorf("Made %d Bakong calls without subscribers", checker.calls)
+	}
+}
+
+func TestServerSentEvents(t *testing.T) {
+	checker := &countingChecker{paid: map[string]bool{}}
+	poller := NewPoller(checker, time.Second)
+	server := httptest.NewServer(NewHandler(poller, AllowAll))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"?md5="+testMD5, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+
+	var statuses []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event StatusEvent
+		json.Unmarshal([]byte(data), &event)
+		statuses = append(statuses, event.Status)
+		if event.Status == StatusUnpaid {
+			checker.pay(testMD5)
+			poller.Poll()
+		}
+	}
+
+	if strings.Join(statuses, ",") != "UNPAID,PAID" {
+		t.Errorf("Stream sent %v, want UNPAID then PAID before closing", statuses)
+	}
+}
+
+func TestLongPoll(t *testing.T) {
+	checker := &countingChecker{paid: map[string]bool{}}
+	poller := NewPoller(checker, time.Second)
+	handler := NewHandler(poller, AllowAll)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?md5="+testMD5+"&wait=0s", nil))
+	var event StatusEvent
+	json.Unmarshal(rec.Body.Bytes(), &event)
+	if event.Status != StatusUnpaid {
+		t.Fatalf("Long poll timed out with %+v, want UNPAID", event)
+	}
+
+	done := make(chan StatusEvent)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?md5="+testMD5+"&status=UNPAID&wait=10s", nil))
+		var event StatusEvent
+		json.Unmarshal(rec.Body.Bytes(), &event)
+		done <- event
+	}()
+
+	// Wait for the long poll to subscribe before paying
+	for {
+		poller.mu.Lock()
+		w := poller.watches[testMD5]
+		subscribed := w != nil && w.subscribers > 0
+		poller.mu.Unlock()
+		if subscribed {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	checker.pay(testMD5)
+	poller.Poll()
+
+	select {
+	case event := <-done:
+		if event.Status != StatusPaid {
+			t.Errorf("Long poll returned %+v, want PAID", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Long poll did not return after the payment")
+	}
+}
+
+func TestRejectsInvalidMD5(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewHandler(NewPoller(&countingChecker{}, 0), AllowAll).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?md5=nope", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Invalid md5 = %d, want 400", rec.Code)
+	}
+}
+
+func TestRejectsUnknownPayments(t *testing.T) {
+	payments := store.NewMemoryStore()
+	payments.Save(context.Background(), store.Payment{MD5: testMD5, Status: store.StatusUnpaid, CreatedAt: time.Now()})
+	checker := &countingChecker{paid: map[string]bool{testMD5: true}}
+	poller := NewPoller(checker, time.Second)
+	handler := NewHandler(poller, KnownPayments(payments))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?md5=ffffffffffffffffffffffffffffffff", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Unknown md5 = %d, want 404", rec.Code)
+	}
+	poller.Poll()
+	if checker.calls != 0 {
+		t.Errorf("Made %d Bakong calls for an unknown md5", checker.calls)
+	}
+}
@@ -0,0 +1,114 @@
+package khqr
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+// maxTemplateLength is the largest length of a data object, as its length is two digits.
+const maxTemplateLength = 99
+
+// Template is a QR compiled once per merchant and terminal. The merchant fields are validated
+// and encoded once; creating a QR only appends the timestamp, amount, currency, additional data and CRC after them.
+type Template struct {
+	khqr      *KHQR
+	static    bool
+	valid     validatedQR // validated merchant and terminal fields, without amount and bill number
+	prefix    []byte      // fields before the timestamp
+	prefixCRC uint16      // CRC-16 state after the prefix
+}
+
+// Method to compile a template from the merchant and terminal fields of the options
+// Amount, Money, BillNumber and CreatedAt are ignored, they are given to Template.Create for every QR
+func (khqr *KHQR) NewTemplate(opts QROptions) (*Template, error) {
+	if opts.Money != nil {
+		opts.Currency = opts.Money.Currency
+	}
+	opts.Amount, opts.Money, opts.BillNumber, opts.CreatedAt = 0, nil, "", time.Time{}
+	v, err := khqr.validate(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var prefix bytes.Buffer
+	e := sdk.NewEncoder(&prefix, khqr.emv)
+	khqr.encodeMerchant(e, opts)
+	if err := e.Err(); err != nil {
+		return nil, err
+	}
+	return &Template{
+		khqr:      khqr,
+		static:    opts.Static,
+		valid:     v,
+		prefix:    prefix.Bytes(),
+		prefixCRC: sdk.UpdateCRC16(0xFFFF, prefix.Bytes()),
+	}, nil
+}
+
+// Currency returns the alphabetic code of the template currency.
+func (t *Template) Currency() string {
+	return t.valid.currency.Code
+}
+
+// CreateQR returns a QR for the amount in the template currency, created now.
+func (t *Template) CreateQR(amount float64, billNumber string) (string, error) {
+	money, err := sdk.MoneyFromFloat(amount, t.Currency())
+	if err != nil {
+		return "", fmt.Errorf("invalid amount %v %s: %w", amount, t.Currency(), err)
+	}
+	return t.Create(money, billNumber, time.Time{})
+}
+
+// Create returns a QR for the amount and bill number, created at createdAt or at the clock time when it is zero.
+// Static templates ignore the amount.
+func (t *Template) Create(amount sdk.Money, billNumber string, createdAt time.Time) (string, error) {
+	khqr := t.khqr
+	if createdAt.IsZero() {
+		createdAt = khqr.clock.Now()
+	}
+	// Only the amount and bill number are checked again, the merchant fields were validated by NewTemplate
+	v := t.valid
+	var fields []*FieldError
+	if !t.static {
+		if !strings.EqualFold(amount.Currency, v.currency.Code) {
+			return "", fmt.Errorf("amount is in %s, the template currency is %s", amount.Currency, v.currency.Code)
+		}
+		v.money = amount
+		if fieldErr := khqr.amountError(amount); fieldErr != nil {
+			fields = append(fields, fieldErr)
+		}
+	}
+	var sub [4]sdk.TLV
+	additionalData := append(sub[:0], v.additionalData...)
+	if fieldErr := tooLong("billNumber", "Bill number", billNumber, khqr.additionalDataField.BillNumberLength); fieldErr != nil {
+		fields = append(fields, fieldErr)
+	} else {
+		// The bill number is the first sub-field, as ordered by AdditionalDataField.Fields
+		additionalData[0] = sdk.TLV{Tag: khqr.additionalDataField.BillNumberTag, Length: len(billNumber), Value: billNumber}
+		if fieldErr := additionalDataTooLong(additionalData); fieldErr != nil {
+			fields = append(fields, fieldErr)
+		}
+	}
+	if len(fields) > 0 {
+		return "", &ValidationError{Fields: fields}
+	}
+
+	// Most QRs fit in the stack buffer, so the only allocation is the final string.
+	// The CRC covers its own tag and continues from the precomputed prefix state.
+	var scratch [256]byte
+	buf := append(scratch[:0], t.prefix...)
+	buf = khqr.appendPayment(buf, v, additionalData, t.static, createdAt)
+	buf = sdk.AppendCRC(buf, khqr.crc.DefaultCRCTag, sdk.UpdateCRC16(t.prefixCRC, buf[len(t.prefix):]))
+	qr := string(buf)
+
+	if khqr.store != nil {
+		if err := khqr.recordQR(qr, createdAt); err != nil {
+			return "", err
+		}
+	}
+	return qr, nil
+}
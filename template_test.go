@@ -0,0 +1,119 @@
+package khqr
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+var templateOptions = QROptions{
+	BankAccount:   "your_name@wing",
+	MerchantName:  "Your Name",
+	MerchantCity:  "Phnom Penh",
+	Currency:      "USD",
+	StoreLabel:    "MShop",
+	PhoneNumber:   "85512345678",
+	TerminalLabel: "Cashier-01",
+}
+
+func TestTemplateMatchesCreateQR(t *testing.T) {
+	khqrInstance := NewKHQR("")
+	template, err := khqrInstance.NewTemplate(templateOptions)
+	if err != nil {
+		t.Fatalf("Failed to compile template: %v", err)
+	}
+	createdAt := time.UnixMilli(1700000000000)
+
+	for _, tc := range []struct {
+		amount float64
+		bill   string
+	}{
+		{10.5, "TRX019283775"},
+		{0.01, ""},
+		{1234567890.99, "INV-2024-000000000000001"},
+	} {
+		opts := templateOptions
+		opts.Amount, opts.BillNumber, opts.CreatedAt = tc.amount, tc.bill, createdAt
+		want, err := khqrInstance.CreateQRWithOptions(opts)
+		if err != nil {
+			t.Fatalf("Failed to create QR: %v", err)
+		}
+		money, _ := sdk.MoneyFromFloat(tc.amount, "USD")
+		got, err := template.Create(money, tc.bill, createdAt)
+		if err != nil || got != want {
+			t.Errorf("Create(%v, %q) = %q, %v, want %q", tc.amount, tc.bill, got, err, want)
+		}
+	}
+
+	static := templateOptions
+	static.Static = true
+	staticTemplate, _ := khqrInstance.NewTemplate(static)
+	static.CreatedAt = createdAt
+	want, _ := khqrInstance.CreateQRWithOptions(static)
+	if got, err := staticTemplate.Create(sdk.Money{}, "", createdAt); err != nil || got != want {
+		t.Errorf("Static Create() = %q, %v, want %q", got, err, want)
+	}
+}
+
+func TestTemplateRejectsInvalidInput(t *testing.T) {
+	template, _ := NewKHQR("").NewTemplate(templateOptions)
+	khr, _ := sdk.NewMoney(1000, "KHR")
+	if _, err := template.Create(khr, "", time.Time{}); err == nil {
+		t.Error("Create accepted an amount in another currency")
+	}
+	if _, err := template.CreateQR(-1, ""); err == nil {
+		t.Error("CreateQR accepted a negative amount")
+	}
+	var validationErr *ValidationError
+	if _, err := template.CreateQR(1, "a bill number that is far too long"); !errors.As(err, &validationErr) || validationErr.Fields[0].Field != "billNumber" {
+		t.Errorf("CreateQR with a bill number longer than 25 characters returned %v, want a billNumber ValidationError", err)
+	}
+}
+
+func TestTemplateCreateAllocations(t *testing.T) {
+	template, err := NewKHQR("").NewTemplate(templateOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount, _ := sdk.NewMoney(1050, "USD")
+	// The QR string is the only allocation, the payment fields are appended on the stack
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := template.Create(amount, "TRX019283775", time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 1 {
+		t.Errorf("Create allocated %v times per QR, want 1", allocs)
+	}
+}
+
+// BenchmarkTemplateCreate compares a template with CreateQR for the same QR.
+func BenchmarkTemplateCreate(b *testing.B) {
+	khqrInstance := NewKHQR("")
+	opts := templateOptions
+	opts.Amount, opts.BillNumber = 10.5, "TRX019283775"
+	b.Run("CreateQR", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := khqrInstance.CreateQRWithOptions(opts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	template, err := khqrInstance.NewTemplate(templateOptions)
+	if err != nil {
+		b.Fatal(err)
+	}
+	amount, _ := sdk.NewMoney(1050, "USD")
+	b.Run("Template", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := template.Create(amount, opts.BillNumber, time.Time{}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
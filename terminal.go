@@ -0,0 +1,116 @@
+package khqr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TerminalOptions configures how a QR code is drawn as text.
+type TerminalOptions struct {
+	// ANSI draws every cell with explicit black and white colours so the QR
+	// scans on both light and dark terminal themes.
+	ANSI bool
+	// Invert swaps the block characters for terminals with a light background.
+	// It has no effect in ANSI mode.
+	Invert bool
+	// MaxWidth is the number of terminal columns available. The QR is scaled up
+	// to the largest whole factor that fits, 0 draws it at its smallest size.
+	MaxWidth int
+	// MaxHeight is the number of terminal rows available, 0 means unlimited.
+	MaxHeight int
+}
+
+// ANSI escape sequences used by the ANSI rendering mode.
+const (
+	ansiDarkOnLight = "\x1b[30;107m"
+	ansiLightOnDark = "\x1b[97;40m"
+	ansiDark        = "\x1b[30;40m"
+	ansiLight       = "\x1b[97;107m"
+	ansiReset       = "\x1b[0m"
+)
+
+// Method to render a QR code string with Unicode half-block characters for display in a terminal
+func (khqr *KHQR) RenderTerminal(qr string, opts TerminalOptions) (string, error) {
+	// Render one pixel per module, including the quiet zone
+	img, err := khqr.QRImage(qr, 1)
+	if err != nil {
+		return "", err
+	}
+	bounds := img.Bounds()
+	modules := bounds.Dx()
+
+	// Each module takes one column and half a row, so the height in rows is half the width
+	scale := 1
+	for opts.MaxWidth > 0 && modules*(scale+1) <= opts.MaxWidth &&
+		(opts.MaxHeight <= 0 || (modules*(scale+1)+1)/2 <= opts.MaxHeight) {
+		scale++
+	}
+	if opts.MaxWidth > 0 && modules > opts.MaxWidth {
+		return "", fmt.Errorf("terminal is too narrow for this QR code: need %d columns, have %d", modules, opts.MaxWidth)
+	}
+	if opts.MaxHeight > 0 && (modules+1)/2 > opts.MaxHeight {
+		return "", fmt.Errorf("terminal is too short for this QR code: need %d rows, have %d", (modules+1)/2, opts.MaxHeight)
+	}
+
+	size := modules * scale
+	dark := func(x, y int) bool {
+		// Rows past the bottom edge belong to the quiet zone
+		if y >= size {
+			return false
+		}
+		r, _, _, _ := img.At(bounds.Min.X+x/scale, bounds.Min.Y+y/scale).RGBA()
+		return r < 0x8000
+	}
+
+	var sb strings.Builder
+	for y := 0; y < size; y += 2 {
+		lastStyle := ""
+		for x := 0; x < size; x++ {
+			top, bottom := dark(x, y), dark(x, y+1)
+			if opts.ANSI {
+				style := ansiStyle(top, bottom)
+				if style != lastStyle {
+					sb.WriteString(style)
+					lastStyle = style
+				}
+				sb.WriteRune('▀')
+				continue
+			}
+			sb.WriteRune(halfBlock(top != opts.Invert, bottom != opts.Invert))
+		}
+		if opts.ANSI {
+			sb.WriteString(ansiReset)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String(), nil
+}
+
+// ansiStyle picks the colours for a cell whose upper half is drawn in the foreground colour.
+func ansiStyle(topDark, bottomDark bool) string {
+	switch {
+	case topDark && bottomDark:
+		return ansiDark
+	case topDark:
+		return ansiDarkOnLight
+	case bottomDark:
+		return ansiLightOnDark
+	default:
+		return ansiLight
+	}
+}
+
+// halfBlock returns the character that fills the given halves of a cell.
+// Light modules are filled so the QR shows on dark terminal backgrounds.
+func halfBlock(topDark, bottomDark bool) rune {
+	switch {
+	case topDark && bottomDark:
+		return ' '
+	case topDark:
+		return '▄'
+	case bottomDark:
+		return '▀'
+	default:
+		return '█'
+	}
+}
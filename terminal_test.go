@@ -0,0 +1,86 @@
+package khqr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRenderTerminalIsScannable(t *testing.T) {
+	khqrInstance := NewKHQR("")
+	text, err := khqrInstance.RenderTerminal(sampleQR, TerminalOptions{})
+	if err != nil {
+		t.Fatalf("Failed to render QR: %v", err)
+	}
+
+	// Paint the half blocks back into pixels, 4x4 per half cell, and scan them
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	width := utf8.RuneCountInString(lines[0])
+	img := image.NewGray(image.Rect(0, 0, width*4, len(lines)*8))
+	for row, line := range lines {
+		for col, ch := range []rune(line) {
+			top := ch == '█' || ch == '▀'
+			bottom := ch == '█' || ch == '▄'
+			for dy := 0; dy < 8; dy++ {
+				light := top
+				if dy >= 4 {
+					light = bottom
+				}
+				for dx := 0; dx < 4; dx++ {
+					if light {
+						img.SetGray(col*4+dx, row*8+dy, color.Gray{Y: 255})
+					}
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to write PNG: %v", err)
+	}
+	decoded, err := khqrInstance.ScanImage(&buf)
+	if err != nil {
+		t.Fatalf("Failed to scan rendered QR: %v", err)
+	}
+	if decoded != sampleQR {
+		t.Errorf("Scanned %q, want %q", decoded, sampleQR)
+	}
+}
+
+func TestRenderTerminalScalesToWidth(t *testing.T) {
+	khqrInstance := NewKHQR("")
+	small, err := khqrInstance.RenderTerminal(sampleQR, TerminalOptions{})
+	if err != nil {
+		t.Fatalf("Failed to render QR: %v", err)
+	}
+	modules := utf8.RuneCountInString(strings.SplitN(small, "\n", 2)[0])
+
+	large, err := khqrInstance.RenderTerminal(sampleQR, TerminalOptions{MaxWidth: modules*2 + 1})
+	if err != nil {
+		t.Fatalf("Failed to render QR: %v", err)
+	}
+	if width := utf8.RuneCountInString(strings.SplitN(large, "\n", 2)[0]); width != modules*2 {
+		t.Errorf("Scaled width = %d, want %d", width, modules*2)
+	}
+
+	if _, err := khqrInstance.RenderTerminal(sampleQR, TerminalOptions{MaxWidth: modules - 1}); err == nil {
+		t.Error("Rendering into a too narrow terminal succeeded, want error")
+	}
+}
+
+func TestRenderTerminalANSI(t *testing.T) {
+	text, err := NewKHQR("").RenderTerminal(sampleQR, TerminalOptions{ANSI: true})
+	if err != nil {
+		t.Fatalf("Failed to render QR: %v", err)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if !strings.HasPrefix(line, "\x1b[") || !strings.HasSuffix(line, ansiReset) {
+			t.Fatalf("Line is not wrapped in ANSI colours: %q", line)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package khqr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrTransactionNotFound is returned by CheckTransaction while the QR is unpaid.
+var ErrTransactionNotFound = errors.New("transaction not found")
+
+// Transaction holds the details of a paid transaction as returned by the Bakong API
+type Transaction struct {
+	Hash                string  `json:"hash"`
+	FromAccountID       string  `json:"fromAccountId"`
+	ToAccountID         string  `json:"toAccountId"`
+	Currency            string  `json:"currency"`
+	Amount              float64 `json:"amount"`
+	Description         string  `json:"description"`
+	CreatedDateMs       int64   `json:"createdDateMs"`
+	AcknowledgedDateMs  int64   `json:"acknowledgedDateMs"`
+	TrackingStatus      string  `json:"trackingStatus"`
+	ReceiverBank        string  `json:"receiverBank"`
+	ReceiverBankAccount string  `json:"receiverBankAccount"`
+	InstructionRef      string  `json:"instructionRef"`
+	ExternalRef         string  `json:"externalRef"`
+}
+
+// CreatedAt returns when the transaction was created
+func (t *Transaction) CreatedAt() time.Time {
+	return time.UnixMilli(t.CreatedDateMs)
+}
+
+// AcknowledgedAt returns when the transaction was acknowledged by the receiving bank
+func (t *Transaction) AcknowledgedAt() time.Time {
+	return time.UnixMilli(t.AcknowledgedDateMs)
+}
+
+// Method to get the details of a paid transaction, ErrTransactionNotFound while it is unpaid
+func (khqr *KHQR) CheckTransaction(md5 string) (*Transaction, error) {
+	if khqr.bakongToken == "" {
+		return nil, fmt.Errorf("the Bakong Developer Token is required for KHQR class initialization")
+	}
+
+	payloadBytes, err := json.Marshal(map[string]string{"md5": md5})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", khqr.bakongAPI+"/check_transaction_by_md5", bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+khqr.bakongToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		ResponseCode    int          `json:"responseCode"`
+		ResponseMessage string       `json:"responseMessage"`
+		ErrorCode       *int         `json:"errorCode"`
+		Data            *Transaction `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case response.ResponseCode == 0 && response.Data != nil:
+		return response.Data, nil
+	case response.ErrorCode != nil && *response.ErrorCode == 6:
+		return nil, fmt.Errorf("your developer token is either incorrect or expired, please renew it through Bakong Developer")
+	}
+	return nil, ErrTransactionNotFound
+}
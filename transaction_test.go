@@ -0,0 +1,40 @@
+package khqr
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckTransaction(t *testing.T) {
+	const paid = "d41d8cd98f00b204e9800998ecf8427e"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/check_transaction_by_md5" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var body struct{ MD5 string }
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.MD5 == paid {
+			w.Write([]byte(`{"responseCode":0,"responseMessage":"Success","errorCode":null,"data":{"hash":"8a3f1c2e9d","fromAccountId":"payer@aclb","toAccountId":"user_name@abaa","currency":"KHR","amount":12500,"createdDateMs":1704096000000,"acknowledgedDateMs":1704096001000}}`))
+			return
+		}
+		w.Write([]byte(`{"responseCode":1,"responseMessage":"Transaction could not be found. Please check and try again.","errorCode":1,"data":null}`))
+	}))
+	defer server.Close()
+
+	k := NewKHQR("token")
+	k.bakongAPI = server.URL
+	tx, err := k.CheckTransaction(paid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.FromAccountID != "payer@aclb" || tx.Amount != 12500 || tx.AcknowledgedAt().UnixMilli() != 1704096001000 {
+		t.Errorf("unexpected transaction %+v", tx)
+	}
+
+	if _, err := k.CheckTransaction("00000000000000000000000000000000"); !errors.Is(err, ErrTransactionNotFound) {
+		t.Errorf("expected ErrTransactionNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package khqr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+// Machine-readable codes of a FieldError
+const (
+	CodeRequired            = "required"             // the field is empty
+	CodeTooLong             = "too_long"             // the field exceeds its EMV length limit
+	CodeInvalidFormat       = "invalid_format"       // e.g. a bank account that is not name@bank
+	CodeInvalidAmount       = "invalid_amount"       // the amount is not a finite decimal number
+	CodeNegativeAmount      = "negative_amount"      // the amount is below zero
+	CodeAmountPrecision     = "amount_precision"     // the amount has more decimals than the currency allows
+	CodeInvalidCurrency     = "invalid_currency"     // the currency is not an ISO 4217 code
+	CodeUnsupportedCurrency = "unsupported_currency" // the currency is not allowed, see SetAllowedCurrencies
+	CodeInvalidPhoneNumber  = "invalid_phone_number" // not a Cambodian mobile number
+)
+
+// FieldError is a problem with one field of QROptions
+type FieldError struct {
+	Field   string `json:"field"` // name of the field in the JSON API, e.g. "merchantName"
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Err     error  `json:"-"` // underlying error, e.g. sdk.ErrAmountPrecision
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError lists every invalid field of QROptions, so a form can show all problems at once
+type ValidationError struct {
+	Fields []*FieldError `json:"fields"`
+}
+
+// Error returns the message of the only invalid field, or all messages prefixed with their field
+func (e *ValidationError) Error() string {
+	if len(e.Fields) == 1 {
+		return e.Fields[0].Message
+	}
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Error()
+	}
+	return fmt.Sprintf("%d invalid fields: %s", len(e.Fields), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the field errors so errors.Is and errors.As can match any of them
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Fields))
+	for i, field := range e.Fields {
+		errs[i] = field
+	}
+	return errs
+}
+
+// Field returns the error of the field, or nil when the field is valid
+func (e *ValidationError) Field(name string) *FieldError {
+	for _, field := range e.Fields {
+		if field.Field == name {
+			return field
+		}
+	}
+	return nil
+}
+
+// validatedQR holds the values resolved while validating QROptions
+type validatedQR struct {
+	currency       sdk.Currency
+	money          sdk.Money
+	additionalData []sdk.TLV
+}
+
+// Method to check every field of the options against the EMV limits without creating a QR
+// The error is a *ValidationError listing all invalid fields, nil when the options are valid
+func (khqr *KHQR) Validate(opts QROptions) error {
+	_, err := khqr.validate(opts)
+	return err
+}
+
+// validate checks every field of the options and returns the resolved currency, amount and additional data
+func (khqr *KHQR) validate(opts QROptions) (validatedQR, error) {
+	var v validatedQR
+	var fields []*FieldError
+	add := func(field, code string, err error) {
+		fields = append(fields, &FieldError{Field: field, Code: code, Message: err.Error(), Err: err})
+	}
+
+	switch err := khqr.globalUniqueIdentifier.Validate(opts.BankAccount); {
+	case err == nil:
+	case opts.BankAccount == "":
+		add("bankAccount", CodeRequired, errors.New("bank account cannot be empty"))
+	case errors.Is(err, sdk.ErrInvalidAccountID):
+		add("bankAccount", CodeInvalidFormat, err)
+	default:
+		add("bankAccount", CodeTooLong, err)
+	}
+	if err := khqr.merchantName.Validate(opts.MerchantName); err != nil {
+		add("merchantName", lengthCode(opts.MerchantName), err)
+	}
+	if err := khqr.merchantCity.Validate(opts.MerchantCity); err != nil {
+		add("merchantCity", lengthCode(opts.MerchantCity), err)
+	}
+
+	currencyCode := opts.Currency
+	if opts.Money != nil {
+		currencyCode = opts.Money.Currency
+	}
+	// The amount is only checked in a known currency so an unsupported one is not also reported as an invalid amount
+	currency, err := khqr.transactionCurrency.Resolve(currencyCode)
+	switch _, known := sdk.LookupCurrency(currencyCode); {
+	case err == nil:
+		v.currency = currency
+		if !opts.Static {
+			if v.money, err = khqr.money(opts); err != nil {
+				add("amount", amountCode(err), err)
+			} else if fieldErr := khqr.amountError(v.money); fieldErr != nil {
+				fields = append(fields, fieldErr)
+			}
+		}
+	case currencyCode == "":
+		add("currency", CodeRequired, errors.New("currency cannot be empty"))
+	case !known:
+		add("currency", CodeInvalidCurrency, err)
+	default:
+		add("currency", CodeUnsupportedCurrency, err)
+	}
+
+	additionalData := []struct {
+		field, label, value string
+		maxLength           int
+	}{
+		{"storeLabel", "Store label", opts.StoreLabel, khqr.additionalDataField.StoreLabelLength},
+		{"billNumber", "Bill number", opts.BillNumber, khqr.additionalDataField.BillNumberLength},
+		{"terminalLabel", "Terminal label", opts.TerminalLabel, khqr.additionalDataField.TerminalLabelLength},
+	}
+	validAdditionalData := true
+	for _, data := range additionalData {
+		if fieldErr := tooLong(data.field, data.label, data.value, data.maxLength); fieldErr != nil {
+			fields = append(fields, fieldErr)
+			validAdditionalData = false
+		}
+	}
+	if opts.PhoneNumber != "" {
+		if _, err := sdk.ParsePhoneNumber(opts.PhoneNumber); err != nil {
+			add("phoneNumber", CodeInvalidPhoneNumber, err)
+			validAdditionalData = false
+		}
+	}
+	// The combined length is only meaningful once every value fits on its own
+	if validAdditionalData {
+		v.additionalData, err = khqr.additionalDataField.Fields(opts.StoreLabel, opts.PhoneNumber, opts.BillNumber, opts.TerminalLabel)
+		if err != nil {
+			add("phoneNumber", CodeTooLong, err)
+		} else if fieldErr := additionalDataTooLong(v.additionalData); fieldErr != nil {
+			fields = append(fields, fieldErr)
+		}
+	}
+
+	if len(fields) > 0 {
+		return v, &ValidationError{Fields: fields}
+	}
+	return v, nil
+}
+
+// amountError returns the error of an amount the QR cannot carry, nil when it is valid
+func (khqr *KHQR) amountError(money sdk.Money) *FieldError {
+	if err := khqr.amount.Validate(money); err != nil {
+		return &FieldError{Field: "amount", Code: amountCode(err), Message: err.Error(), Err: err}
+	}
+	return nil
+}
+
+// tooLong returns the error of a value longer than maxLength, nil when it fits
+func tooLong(field, label, value string, maxLength int) *FieldError {
+	if len(value) <= maxLength {
+		return nil
+	}
+	err := fmt.Errorf("%s cannot exceed %d characters. Your input length: %d characters", label, maxLength, len(value))
+	return &FieldError{Field: field, Code: CodeTooLong, Message: err.Error(), Err: err}
+}
+
+// additionalDataTooLong returns the error of additional data that does not fit in its template, nil when it fits
+func additionalDataTooLong(fields []sdk.TLV) *FieldError {
+	length := templateLength(fields)
+	if length <= maxTemplateLength {
+		return nil
+	}
+	err := fmt.Errorf("additional data cannot exceed %d characters. Your input length: %d characters", maxTemplateLength, length)
+	return &FieldError{Field: "additionalData", Code: CodeTooLong, Message: err.Error(), Err: err}
+}
+
+// lengthCode tells an empty required value from one that is too long
+func lengthCode(value string) string {
+	if value == "" {
+		return CodeRequired
+	}
+	return CodeTooLong
+}
+
+// amountCode maps an amount error to its code
+func amountCode(err error) string {
+	switch {
+	case errors.Is(err, sdk.ErrNegativeAmount):
+		return CodeNegativeAmount
+	case errors.Is(err, sdk.ErrAmountPrecision):
+		return CodeAmountPrecision
+	case errors.Is(err, sdk.ErrAmountTooLong):
+		return CodeTooLong
+	}
+	return CodeInvalidAmount
+}
+
+// templateLength returns the length of the value of a template holding the sub-fields
+func templateLength(fields []sdk.TLV) int {
+	length := 0
+	for _, field := range fields {
+		length += 4 + len(field.Value)
+	}
+	return length
+}
@@ -0,0 +1,93 @@
+package khqr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chhunneng/bakong-khqr/sdk"
+)
+
+func TestValidateReportsEveryField(t *testing.T) {
+	k := NewKHQR("")
+	err := k.Validate(QROptions{
+		BankAccount:   "your name",
+		MerchantName:  "",
+		MerchantCity:  "Phnom Penh Capital City",
+		Amount:        -100,
+		Currency:      "KHR",
+		PhoneNumber:   "123",
+		BillNumber:    strings.Repeat("B", 26),
+		TerminalLabel: "Cashier-01",
+	})
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("Validate() = %v, want a *ValidationError", err)
+	}
+	want := map[string]string{
+		"bankAccount":  CodeInvalidFormat,
+		"merchantName": CodeRequired,
+		"merchantCity": CodeTooLong,
+		"amount":       CodeNegativeAmount,
+		"billNumber":   CodeTooLong,
+		"phoneNumber":  CodeInvalidPhoneNumber,
+	}
+	if len(validation.Fields) != len(want) {
+		t.Errorf("Validate() returned %d fields, want %d: %v", len(validation.Fields), len(want), err)
+	}
+	for field, code := range want {
+		if got := validation.Field(field); got == nil || got.Code != code {
+			t.Errorf("field %s = %+v, want code %s", field, got, code)
+		}
+	}
+	if !errors.Is(err, sdk.ErrNegativeAmount) || !errors.Is(err, sdk.ErrInvalidPhoneNumber) {
+		t.Error("expected the underlying errors to be matched by errors.Is")
+	}
+
+	// CreateQR fails with the same error and a single invalid field keeps its own message
+	if _, err := k.CreateQR("your_name@wing", "Your Name", "Phnom Penh", 10, "EUR", "", "", "", "", false); !errors.As(err, &validation) ||
+		validation.Fields[0].Code != CodeUnsupportedCurrency || err.Error() != validation.Fields[0].Message {
+		t.Errorf("CreateQR() with an unsupported currency = %v", err)
+	}
+	if err := k.Validate(QROptions{BankAccount: "your_name@wing", MerchantName: "Your Name", MerchantCity: "Phnom Penh", Currency: "USD", Amount: 1.25}); err != nil {
+		t.Errorf("Validate() of valid options = %v", err)
+	}
+}
+
+func TestSetAllowedCurrencies(t *testing.T) {
+	k := NewKHQR("")
+	opts := QROptions{BankAccount: "your_name@wing", MerchantName: "Your Name", MerchantCity: "Phnom Penh", Currency: "THB", Amount: 1.25}
+	if err := k.SetAllowedCurrencies("THB"); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Validate(opts); err != nil {
+		t.Errorf("Validate() of an allowed currency = %v", err)
+	}
+	if err := k.SetAllowedCurrencies("XXX1"); err == nil {
+		t.Error("SetAllowedCurrencies accepted an unknown code")
+	}
+
+	// No codes restores USD and KHR rather than rejecting every currency
+	if err := k.SetAllowedCurrencies(); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Validate(opts); err == nil {
+		t.Error("THB is still allowed after restoring the default")
+	}
+	opts.Currency = "KHR"
+	opts.Amount = 1000
+	if err := k.Validate(opts); err != nil {
+		t.Errorf("Validate() of KHR after restoring the default = %v", err)
+	}
+}
+
+func TestCreateQRRoundsComputedAmounts(t *testing.T) {
+	price, quantity := 0.1, 3.0
+	qr, err := NewKHQR("").CreateQRWithOptions(QROptions{BankAccount: "your_name@wing", MerchantName: "Your Name", MerchantCity: "Phnom Penh", Currency: "USD", Amount: price * quantity})
+	if err != nil {
+		t.Fatalf("CreateQR with %v USD failed: %v", price*quantity, err)
+	}
+	if !strings.Contains(qr, "54030.3") {
+		t.Errorf("CreateQR with %v USD encoded %s, want the amount 0.3", price*quantity, qr)
+	}
+}
@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the timestamp and HMAC-SHA256 signature of every delivery.
+const SignatureHeader = "X-KHQR-Signature"
+
+// Sign returns the SignatureHeader value for a body sent at the given time.
+// The signature covers "<unix seconds>.<body>" so a captured request cannot be replayed later.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + computeSignature(secret, unix, body)
+}
+
+// VerifySignature checks a SignatureHeader value on a received webhook.
+// Deliveries signed more than tolerance away from now are rejected, a zero tolerance skips that check.
+func VerifySignature(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	if secret == "" {
+		return ErrMissingSecret
+	}
+	var unix, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signature = value
+		}
+	}
+	if unix == "" || signature == "" {
+		return errors.New("malformed signature header")
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(seconds, 0))
+		if age > tolerance || age < -tolerance {
+			return errors.New("signature timestamp is outside the tolerance")
+		}
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(computeSignature(secret, unix, body))) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// computeSignature returns the hex HMAC-SHA256 of "<unix>.<body>".
+func computeSignature(secret, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
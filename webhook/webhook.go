@@ -0,0 +1,303 @@
+// Package webhook turns Bakong payment polling into webhook notifications.
+//
+// A Dispatcher polls the md5 of every tracked QR and, once it is paid, POSTs
+// a signed JSON Event to the merchant's URL. Deliveries are retried with
+// exponential backoff and end up in the dead-letter list when they keep failing.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// EventPaymentPaid is the type of the event sent when a tracked QR is paid.
+const EventPaymentPaid = "payment.paid"
+
+// Defaults applied when a Config leaves a setting unset.
+const (
+	DefaultPollInterval = 5 * time.Second
+	DefaultMaxAttempts  = 8
+	DefaultBackoff      = time.Second
+	DefaultMaxBackoff   = 5 * time.Minute
+	maxBulkMD5Count     = 50
+)
+
+// PaymentChecker reports which md5s are paid. It is implemented by *khqr.KHQR.
+type PaymentChecker interface {
+	CheckBulkPayments(md5List []string) ([]string, error)
+}
+
+// ErrMissingSecret is returned for an empty signing secret, as anyone can forge a signature made with an empty key.
+var ErrMissingSecret = errors.New("webhook secret is required")
+
+// Config configures a Dispatcher.
+type Config struct {
+	// URL receives events for payments tracked without their own URL.
+	URL string
+	// Secret signs every delivery, see Sign and VerifySignature. It is required.
+	Secret       string
+	PollInterval time.Duration
+	MaxAttempts  int
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	HTTPClient   *http.Client
+	// OnDeadLetter is called when a delivery is given up on.
+	OnDeadLetter func(DeadLetter)
+	// OnError is called when polling Bakong fails, the poll is retried on the next tick.
+	OnError func(error)
+}
+
+// Payment is a QR whose payment should be reported to a webhook.
+type Payment struct {
+	MD5       string
+	Reference string    // merchant reference echoed in the event, e.g. an order ID
+	URL       string    // overrides Config.URL for this payment
+	ExpiresAt time.Time // stop polling after this time, zero polls until paid
+}
+
+// Event is the JSON body POSTed to the webhook URL.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	MD5       string    `json:"md5"`
+	Reference string    `json:"reference,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DeadLetter is an event whose delivery was given up on.
+type DeadLetter struct {
+	Event    Event
+	URL      string
+	Attempts int
+	Err      error
+}
+
+// Dispatcher polls tracked payments and delivers webhook events.
+type Dispatcher struct {
+	checker PaymentChecker
+	config  Config
+	now     func() time.Time
+
+	mu          sync.Mutex
+	tracked     map[string]Payment
+	deadLetters []DeadLetter
+	deliveries  sync.WaitGroup
+}
+
+// New initializes and returns a Dispatcher polling the given checker, or ErrMissingSecret.
+func New(checker PaymentChecker, config Config) (*Dispatcher, error) {
+	if config.Secret == "" {
+		return nil, ErrMissingSecret
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = DefaultMaxAttempts
+	}
+	if config.Backoff <= 0 {
+		config.Backoff = DefaultBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Dispatcher{
+		checker: checker,
+		config:  config,
+		now:     time.Now,
+		tracked: make(map[string]Payment),
+	}, nil
+}
+
+// Track starts polling the payment. Tracking an md5 again replaces its settings.
+func (d *Dispatcher) Track(payment Payment) error {
+	if payment.MD5 == "" {
+		return errors.New("payment md5 is required")
+	}
+	if payment.URL == "" && d.config.URL == "" {
+		return errors.New("payment has no webhook URL and no default URL is configured")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tracked[payment.MD5] = payment
+	return nil
+}
+
+// Untrack stops polling the md5 without sending an event.
+func (d *Dispatcher) Untrack(md5 string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.tracked, md5)
+}
+
+// Tracked returns the number of payments still being polled.
+func (d *Dispatcher) Tracked() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.tracked)
+}
+
+// DeadLetters returns the events that could not be delivered.
+func (d *Dispatcher) DeadLetters() []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DeadLetter(nil), d.deadLetters...)
+}
+
+// Run polls tracked payments every PollInterval until the context is cancelled,
+// then waits for in-flight deliveries to stop.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+	defer d.deliveries.Wait()
+
+	for {
+		d.Poll(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll checks every tracked payment once and starts delivering events for the paid ones.
+func (d *Dispatcher) Poll(ctx context.Context) {
+	now := d.now()
+	d.mu.Lock()
+	md5List := make([]string, 0, len(d.tracked))
+	for md5, payment := range d.tracked {
+		if !payment.ExpiresAt.IsZero() && now.After(payment.ExpiresAt) {
+			delete(d.tracked, md5)
+			continue
+		}
+		md5List = append(md5List, md5)
+	}
+	d.mu.Unlock()
+
+	// Bakong accepts at most 50 hashes per bulk check
+	for start := 0; start < len(md5List); start += maxBulkMD5Count {
+		end := min(start+maxBulkMD5Count, len(md5List))
+		paid, err := d.checker.CheckBulkPayments(md5List[start:end])
+		if err != nil {
+			if d.config.OnError != nil {
+				d.config.OnError(err)
+			}
+			continue
+		}
+		for _, md5 := range paid {
+			d.mu.Lock()
+			payment, ok := d.tracked[md5]
+			delete(d.tracked, md5)
+			d.mu.Unlock()
+			if !ok {
+				continue
+			}
+
+			d.deliveries.Add(1)
+			go func() {
+				defer d.deliveries.Done()
+				d.deliver(ctx, payment, now)
+			}()
+		}
+	}
+}
+
+// Wait blocks until every delivery started by Poll has finished.
+func (d *Dispatcher) Wait() {
+	d.deliveries.Wait()
+}
+
+// deliver sends the paid event for the payment, retrying until it succeeds or is dead-lettered.
+func (d *Dispatcher) deliver(ctx context.Context, payment Payment, paidAt time.Time) {
+	event := Event{
+		ID:        EventID(EventPaymentPaid, payment.MD5),
+		Type:      EventPaymentPaid,
+		MD5:       payment.MD5,
+		Reference: payment.Reference,
+		CreatedAt: paidAt.UTC(),
+	}
+	url := payment.URL
+	if url == "" {
+		url = d.config.URL
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.deadLetter(DeadLetter{Event: event, URL: url, Err: err})
+		return
+	}
+
+	backoff := d.config.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.send(ctx, url, event.ID, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= d.config.MaxAttempts {
+			d.deadLetter(DeadLetter{Event: event, URL: url, Attempts: attempt, Err: err})
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			d.deadLetter(DeadLetter{Event: event, URL: url, Attempts: attempt, Err: ctx.Err()})
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, d.config.MaxBackoff)
+	}
+}
+
+// send makes one delivery attempt and reports whether a failure is worth retrying.
+func (d *Dispatcher) send(ctx context.Context, url, eventID string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", eventID)
+	req.Header.Set(SignatureHeader, Sign(d.config.Secret, d.now(), body))
+
+	resp, err := d.config.HTTPClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook returned %s", resp.Status)
+	// Other client errors mean the receiver rejected the event and will keep rejecting it
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+	return retry, err
+}
+
+// deadLetter records an undeliverable event and reports it to OnDeadLetter.
+func (d *Dispatcher) deadLetter(letter DeadLetter) {
+	d.mu.Lock()
+	d.deadLetters = append(d.deadLetters, letter)
+	d.mu.Unlock()
+	if d.config.OnDeadLetter != nil {
+		d.config.OnDeadLetter(letter)
+	}
+}
+
+// EventID returns the idempotency key of an event, which is stable across retries and restarts.
+func EventID(eventType, md5 string) string {
+	sum := sha256.Sum256([]byte(eventType + ":" + md5))
+	return "evt_" + hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeChecker struct {
+	paid map[string]bool
+}
+
+func (f *fakeChecker) CheckBulkPayments(md5List []string) ([]string, error) {
+	var paid []string
+	for _, md5 := range md5List {
+		if f.paid[md5] {
+			paid = append(paid, md5)
+		}
+	}
+	return paid, nil
+}
+
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newDispatcher(checker PaymentChecker, url string) *Dispatcher {
+	d, err := New(checker, Config{URL: url, Secret: "secret", Backoff: time.Millisecond, MaxAttempts: 3})
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestDeliversSignedEventWhenPaid(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusOK}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	checker := &fakeChecker{paid: map[string]bool{}}
+	d := newDispatcher(checker, server.URL)
+	d.Track(Payment{MD5: "paid-md5", Reference: "order-1"})
+	d.Track(Payment{MD5: "unpaid-md5"})
+
+	d.Poll(context.Background())
+	d.Wait()
+	if len(rc.requests) != 0 {
+		t.Fatalf("Delivered %d events before payment", len(rc.requests))
+	}
+
+	checker.paid["paid-md5"] = true
+	d.Poll(context.Background())
+	d.Wait()
+
+	if len(rc.requests) != 2 {
+		t.Fatalf("Made %d delivery attempts, want 2", len(rc.requests))
+	}
+	if d.Tracked() != 1 {
+		t.Errorf("Tracked = %d, want only the unpaid payment", d.Tracked())
+	}
+	for i, req := range rc.requests {
+		if err := VerifySignature("secret", req.Header.Get(SignatureHeader), rc.bodies[i], time.Minute, time.Now()); err != nil {
+			t.Errorf("Attempt %d has an invalid signature: %v", i+1, err)
+		}
+		if key := req.Header.Get("Idempotency-Key"); key != EventID(EventPaymentPaid, "paid-md5") {
+			t.Errorf("Attempt %d has idempotency key %q", i+1, key)
+		}
+	}
+
+	var event Event
+	if err := json.Unmarshal(rc.bodies[1], &event); err != nil {
+		t.Fatalf("Invalid event body: %v", err)
+	}
+	if event.Type != EventPaymentPaid || event.MD5 != "paid-md5" || event.Reference != "order-1" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+func TestDeadLettersFailedDeliveries(t *testing.T) {
+	rc := &receiver{statuses: []int{http.StatusBadRequest, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	checker := &fakeChecker{paid: map[string]bool{"rejected": true}}
+	d := newDispatcher(checker, server.URL)
+	d.Track(Payment{MD5: "rejected"})
+	d.Poll(context.Background())
+	d.Wait()
+
+	checker.paid["unavailable"] = true
+	d.Track(Payment{MD5: "unavailable"})
+	d.Poll(context.Background())
+	d.Wait()
+
+	letters := d.DeadLetters()
+	if len(letters) != 2 {
+		t.Fatalf("Got %d dead letters, want 2", len(letters))
+	}
+	if letters[0].Event.MD5 != "rejected" || letters[0].Attempts != 1 {
+		t.Errorf("Client error was retried: %+v", letters[0])
+	}
+	if letters[1].Event.MD5 != "unavailable" || letters[1].Attempts != 3 {
+		t.Errorf("Server error was not retried up to MaxAttempts: %+v", letters[1])
+	}
+}
+
+func TestStopsTrackingExpiredPayments(t *testing.T) {
+	d := newDispatcher(&fakeChecker{}, "http://example.invalid")
+	d.Track(Payment{MD5: "expired", ExpiresAt: time.Now().Add(-time.Second)})
+	d.Track(Payment{MD5: "active", ExpiresAt: time.Now().Add(time.Hour)})
+	d.Poll(context.Background())
+	if d.Tracked() != 1 {
+		t.Errorf("Tracked = %d, want 1", d.Tracked())
+	}
+}
+
+func TestVerifySignatureRejectsTampering(t *testing.T) {
+	now := time.Now()
+	header := Sign("secret", now, []byte(`{"md5":"a"}`))
+	if err := VerifySignature("secret", header, []byte(`{"md5":"b"}`), time.Minute, now); err == nil {
+		t.Error("Tampered body was accepted")
+	}
+	if err := VerifySignature("other", header, []byte(`{"md5":"a"}`), time.Minute, now); err == nil {
+		t.Error("Wrong secret was accepted")
+	}
+	if err := VerifySignature("secret", header, []byte(`{"md5":"a"}`), time.Minute, now.Add(time.Hour)); err == nil {
+		t.Error("Stale signature was accepted")
+	}
+	forged := Sign("", now, []byte(`{"md5":"a"}`))
+	if err := VerifySignature("", forged, []byte(`{"md5":"a"}`), time.Minute, now); !errors.Is(err, ErrMissingSecret) {
+		t.Errorf("Signature with an empty secret returned %v, want ErrMissingSecret", err)
+	}
+}
+
+func TestNewRequiresSecret(t *testing.T) {
+	if _, err := New(&fakeChecker{}, Config{URL: "http://example.invalid"}); !errors.Is(err, ErrMissingSecret) {
+		t.Errorf("New without a secret returned %v, want ErrMissingSecret", err)
+	}
+}